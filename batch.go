@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/la0rg/highloadcup/store"
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	ErrBatchOperation = errors.New("Operation should be create or update")
+	ErrBatchEntity    = errors.New("Entity should be users, locations or visits")
+	ErrBatchTooLarge  = errors.New("Too many operations in the batch")
+)
+
+// Atomic is the query argument of POST /batch
+const Atomic = "atomic"
+
+// Batch applies creates and updates of users, locations and visits
+// body is a JSON array or newline delimited JSON of {"op", "entity", "id", "data"} objects
+// success - 200 with {"applied", "results"}, results keep the status and the error of every operation
+// incorrect body - 400
+// atomic=true applies all the operations or none of them
+func Batch(ctx *fasthttp.RequestCtx) {
+	atomic := false
+	args := ctx.QueryArgs()
+	if args.Has(Atomic) {
+		switch string(args.Peek(Atomic)) {
+		case "true", "1":
+			atomic = true
+		case "false", "0":
+		default:
+			writeError(ctx, http.StatusBadRequest, argError(Atomic))
+			return
+		}
+	}
+
+	items, err := splitBatch(ctx.PostBody())
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if len(items) > cfg.Limits.MaxBatchSize {
+		writeError(ctx, http.StatusBadRequest, ErrBatchTooLarge)
+		return
+	}
+
+	errs := make([]error, len(items))
+	ops := make([]store.Op, 0, len(items))
+	// positions of ops in items
+	positions := make([]int, 0, len(items))
+	for i, item := range items {
+		op, err := parseBatchOp(item)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		ops = append(ops, op)
+		positions = append(positions, i)
+	}
+	if atomic && len(ops) < len(items) {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = store.ErrBatchAborted
+			}
+		}
+	} else {
+		for i, err := range dataStore.Batch(ops, atomic) {
+			errs[positions[i]] = err
+		}
+	}
+
+	response := model.BatchResponse{Results: make([]model.BatchResult, len(items))}
+	for i, err := range errs {
+		if err != nil {
+			response.Results[i] = model.BatchResult{Status: errorStatus(err), Error: newError(ctx, err)}
+			continue
+		}
+		response.Results[i].Status = http.StatusOK
+		response.Applied++
+	}
+	err = writeStructAsJSON(ctx, response)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// splitBatch returns the raw operations of a JSON array or of newline delimited JSON
+func splitBatch(body []byte) ([][]byte, error) {
+	body = bytes.TrimSpace(body)
+	var items [][]byte
+	if len(body) > 0 && body[0] == '[' {
+		in := jlexer.Lexer{Data: body}
+		in.Delim('[')
+		for !in.IsDelim(']') {
+			items = append(items, in.Raw())
+			in.WantComma()
+		}
+		in.Delim(']')
+		in.Consumed()
+		return items, in.Error()
+	}
+	for _, line := range bytes.Split(body, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			items = append(items, line)
+		}
+	}
+	return items, nil
+}
+
+// parseBatchOp decodes an operation of the batch, data is checked like the body of the single entity requests
+func parseBatchOp(item []byte) (store.Op, error) {
+	var op store.Op
+	var b model.BatchOp
+	err := easyjson.Unmarshal(item, &b)
+	if err != nil {
+		return op, err
+	}
+	switch b.Op {
+	case "create":
+	case "update":
+		if !b.ID.Defined {
+			return op, &model.FieldError{Field: "id", Err: store.ErrRequiredFields}
+		}
+		op.Update, op.ID = true, b.ID.V
+	default:
+		return op, &model.FieldError{Field: "op", Err: ErrBatchOperation}
+	}
+	if len(b.Data) == 0 {
+		return op, &model.FieldError{Field: "data", Err: store.ErrRequiredFields}
+	}
+	switch b.Entity {
+	case "users":
+		op.User = &model.User{}
+		err = op.User.UnmarshalStrict(b.Data)
+	case "locations":
+		op.Location = &model.Location{}
+		err = op.Location.UnmarshalStrict(b.Data)
+	case "visits":
+		op.Visit = &model.Visit{}
+		err = op.Visit.UnmarshalStrict(b.Data)
+	default:
+		return op, &model.FieldError{Field: "entity", Err: ErrBatchEntity}
+	}
+	return op, err
+}
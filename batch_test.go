@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	batchUser     = `{"op": "create", "entity": "users", "data": {"id": 1, "email": "a@b.c", "first_name": "a", "last_name": "b", "gender": "m", "birth_date": 0}}`
+	batchLocation = `{"op": "create", "entity": "locations", "data": {"id": 1, "place": "p", "country": "c", "city": "c", "distance": 1}}`
+	batchVisit    = `{"op": "create", "entity": "visits", "data": {"id": 1, "user": 1, "location": 1, "visited_at": 1, "mark": 3}}`
+	batchUpdate   = `{"op": "update", "entity": "users", "id": 1, "data": {"first_name": "x"}}`
+)
+
+func batchResponse(t *testing.T, handler fasthttp.RequestHandler, uri, body string) model.BatchResponse {
+	ctx := do(handler, "POST", uri, body)
+	if status := ctx.Response.StatusCode(); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d, %s", status, ctx.Response.Body())
+	}
+	var response model.BatchResponse
+	if err := easyjson.Unmarshal(ctx.Response.Body(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func statuses(response model.BatchResponse) []int {
+	s := make([]int, len(response.Results))
+	for i, r := range response.Results {
+		s[i] = r.Status
+	}
+	return s
+}
+
+func TestBatch_Formats(t *testing.T) {
+	for _, body := range []string{
+		"[" + strings.Join([]string{batchUser, batchLocation, batchVisit, batchUpdate}, ",\n") + "]",
+		strings.Join([]string{batchUser, batchLocation, "", batchVisit, batchUpdate}, "\n") + "\n",
+	} {
+		response := batchResponse(t, newTestHandler(t), "/batch", body)
+		if response.Applied != 4 || len(response.Results) != 4 {
+			t.Errorf("expected 4 applied operations, got %+v", response)
+		}
+		if u, ok := dataStore.GetUserByID(1); !ok || u.FirstName.V != "x" {
+			t.Errorf("expected the updated user, got %v", u)
+		}
+		if _, ok := dataStore.GetVisitByID(1); !ok {
+			t.Error("visit is not created")
+		}
+	}
+
+	ctx := do(newTestHandler(t), "POST", "/batch", "["+batchUser+",")
+	if status := ctx.Response.StatusCode(); status != http.StatusBadRequest {
+		t.Errorf("expected status 400 of the broken array, got %d", status)
+	}
+	if e := errorBody(t, ctx); e.Code != CodeInvalidJSON {
+		t.Errorf("expected invalid_json, got %+v", e)
+	}
+}
+
+func TestBatch_MaxSize(t *testing.T) {
+	handler := newTestHandler(t)
+	cfg.Limits.MaxBatchSize = 2
+	body := strings.Join([]string{batchUser, batchLocation, batchVisit}, "\n")
+	ctx := do(handler, "POST", "/batch", body)
+	if status := ctx.Response.StatusCode(); status != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", status)
+	}
+	if e := errorBody(t, ctx); e.Code != CodeBatchTooLarge {
+		t.Errorf("expected batch_too_large, got %+v", e)
+	}
+	if _, ok := dataStore.GetUserByID(1); ok {
+		t.Error("operation of the rejected batch is applied")
+	}
+	if response := batchResponse(t, handler, "/batch", strings.Join([]string{batchUser, batchLocation}, "\n")); response.Applied != 2 {
+		t.Errorf("expected the batch of the max size applied, got %+v", response)
+	}
+}
+
+func TestBatch_Atomic(t *testing.T) {
+	handler := newTestHandler(t)
+	// the second create of the user fails in the middle
+	body := strings.Join([]string{batchUser, batchLocation, batchUser, batchVisit, batchUpdate}, "\n")
+
+	response := batchResponse(t, handler, "/batch?atomic=true", body)
+	if response.Applied != 0 {
+		t.Errorf("expected nothing applied, got %+v", response)
+	}
+	for i, r := range response.Results {
+		if r.Error == nil {
+			t.Errorf("operation %d: expected an error, got %+v", i, r)
+			continue
+		}
+		code := CodeAborted
+		if i == 2 {
+			code = CodeAlreadyExists
+		}
+		if r.Error.Code != code {
+			t.Errorf("operation %d: expected %s, got %+v", i, code, r.Error)
+		}
+	}
+	if _, ok := dataStore.GetUserByID(1); ok {
+		t.Error("user of the failed atomic batch is kept")
+	}
+	if _, ok := dataStore.GetLocationByID(1); ok {
+		t.Error("location of the failed atomic batch is kept")
+	}
+	if seq := dataStore.LastEventSeq(); seq != 0 {
+		t.Errorf("failed atomic batch published %d events", seq)
+	}
+
+	// the malformed operation aborts the atomic batch before it is applied
+	malformed := `{"op": "delete", "entity": "users", "id": 1, "data": {}}`
+	response = batchResponse(t, handler, "/batch?atomic=1", strings.Join([]string{batchUser, malformed}, "\n"))
+	if response.Applied != 0 || response.Results[0].Error == nil || response.Results[0].Error.Code != CodeAborted ||
+		response.Results[1].Error == nil || response.Results[1].Error.Code != CodeInvalidOperation {
+		t.Errorf("expected the aborted batch, got %+v", response)
+	}
+
+	// without atomic the operations are independent
+	response = batchResponse(t, handler, "/batch", body)
+	expected := []int{http.StatusOK, http.StatusOK, http.StatusBadRequest, http.StatusOK, http.StatusOK}
+	if response.Applied != 4 || !reflect.DeepEqual(statuses(response), expected) {
+		t.Errorf("expected statuses %v, got %+v", expected, response)
+	}
+}
@@ -0,0 +1,123 @@
+// Package clock tells the current time of the data and converts ages to birth date boundaries.
+//
+// The ages are counted in calendar years of a time zone: a person is N years old
+// from the start of the day of the N-th birthday in that zone.
+// The birthday of the people born on February 29 is March 1 in the common years.
+package clock
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrClockMode = errors.New("Clock mode should be options, fixed or wall")
+	ErrTime      = errors.New("Time should be unix seconds or RFC3339")
+	ErrOptions   = errors.New("Options file should start with the unix time of the data")
+)
+
+// Modes of the clock
+const (
+	// ModeOptions is fixed at the time of the first line of the options file
+	ModeOptions = "options"
+	// ModeFixed is fixed at the given time
+	ModeFixed = "fixed"
+	// ModeWall is the real time
+	ModeWall = "wall"
+)
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// Fixed is the clock stopped at the time
+type Fixed time.Time
+
+// Now returns the time of the clock
+func (f Fixed) Now() time.Time {
+	return time.Time(f)
+}
+
+type wall struct{}
+
+func (wall) Now() time.Time {
+	return time.Now()
+}
+
+// Wall is the real time clock
+var Wall Clock = wall{}
+
+// New creates the clock of the mode, value is the options file for ModeOptions
+// and the time for ModeFixed (see ParseTime), it is not used by ModeWall
+func New(mode, value string) (Clock, error) {
+	switch mode {
+	case ModeOptions:
+		return FromOptions(value)
+	case ModeFixed:
+		t, err := ParseTime(value)
+		if err != nil {
+			return nil, err
+		}
+		return Fixed(t), nil
+	case ModeWall:
+		return Wall, nil
+	}
+	return nil, ErrClockMode
+}
+
+// FromOptions returns the clock fixed at the unix time of the first line of the options file
+func FromOptions(path string) (Clock, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrOptions
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(scanner.Text()), 10, 64)
+	if err != nil {
+		return nil, ErrOptions
+	}
+	return Fixed(time.Unix(sec, 0)), nil
+}
+
+// ParseTime reads unix seconds or RFC3339 time
+func ParseTime(value string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, ErrTime
+	}
+	return t, nil
+}
+
+// AgeBoundary returns the unix time T such that the people born before T
+// are at least years old at now in the time zone loc, the people born at T or later are younger.
+func AgeBoundary(now time.Time, years int, loc *time.Location) int64 {
+	now = now.In(loc)
+	year, month, day := now.Date()
+	year -= years
+	// on February 29 the people born on February 28 of a common year have the birthday,
+	// the ones born on March 1 do not
+	if month == time.February && day == 29 && !isLeap(year) {
+		day = 28
+	}
+	// the end of the day of the birth of the youngest people that are years old
+	return time.Date(year, month, day+1, 0, 0, 0, 0, loc).Unix()
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
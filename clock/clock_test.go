@@ -0,0 +1,77 @@
+package clock
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int, loc *time.Location) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+func TestAgeBoundary(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	cases := []struct {
+		now      time.Time
+		years    int
+		loc      *time.Location
+		boundary time.Time
+	}{
+		// the birthday counts from the start of the day
+		{time.Date(2017, time.August, 10, 15, 30, 0, 0, time.UTC), 20, time.UTC, date(1997, time.August, 11, time.UTC)},
+		{date(2017, time.August, 10, time.UTC), 0, time.UTC, date(2017, time.August, 11, time.UTC)},
+		// February 29 of a leap year, the years ago are common
+		{date(2020, time.February, 29, time.UTC), 1, time.UTC, date(2019, time.March, 1, time.UTC)},
+		// and leap
+		{date(2020, time.February, 29, time.UTC), 4, time.UTC, date(2016, time.March, 1, time.UTC)},
+		// the people born on February 29 are not years old on February 28
+		{date(2019, time.February, 28, time.UTC), 3, time.UTC, date(2016, time.February, 29, time.UTC)},
+		{date(2019, time.March, 1, time.UTC), 3, time.UTC, date(2016, time.March, 2, time.UTC)},
+		// 1900 is not leap
+		{date(2000, time.February, 29, time.UTC), 100, time.UTC, date(1900, time.March, 1, time.UTC)},
+		// 22:00 UTC is the next day in Moscow
+		{time.Date(2017, time.August, 10, 22, 0, 0, 0, time.UTC), 20, msk, date(1997, time.August, 12, msk)},
+		{time.Date(2017, time.August, 10, 22, 0, 0, 0, time.UTC), 20, time.UTC, date(1997, time.August, 11, time.UTC)},
+	}
+	for _, c := range cases {
+		if b := AgeBoundary(c.now, c.years, c.loc); b != c.boundary.Unix() {
+			t.Errorf("%v, %d years in %v: expected %v, got %v", c.now, c.years, c.loc, c.boundary, time.Unix(b, 0).In(c.loc))
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	f, err := ioutil.TempFile("", "hlc-options")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("1503695452\n1\n")
+	f.Close()
+
+	c, err := New(ModeOptions, f.Name())
+	if err != nil || c.Now().Unix() != 1503695452 {
+		t.Errorf("expected the time of the options, got %v, %v", c, err)
+	}
+	if _, err := New(ModeOptions, f.Name()+".missing"); err == nil {
+		t.Error("missing options file is ignored")
+	}
+	c, err = New(ModeFixed, "2017-08-25T21:10:52Z")
+	if err != nil || c.Now().Unix() != 1503695452 {
+		t.Errorf("expected the fixed time, got %v, %v", c, err)
+	}
+	if c, err = New(ModeFixed, "1503695452"); err != nil || c.Now().Unix() != 1503695452 {
+		t.Errorf("expected the fixed unix time, got %v, %v", c, err)
+	}
+	if _, err := New(ModeFixed, "yesterday"); err != ErrTime {
+		t.Errorf("expected ErrTime, got %v", err)
+	}
+	if c, err := New(ModeWall, ""); err != nil || time.Since(c.Now()) > time.Minute {
+		t.Errorf("expected the wall clock, got %v, %v", c, err)
+	}
+	if _, err := New("sundial", ""); err != ErrClockMode {
+		t.Errorf("expected ErrClockMode, got %v", err)
+	}
+}
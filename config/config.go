@@ -0,0 +1,237 @@
+// Package config collects the settings of the server from the command line flags,
+// the environment variables and an optional config file.
+//
+// Every setting has a flag name, e.g. -gc-percent. The same name is used in the config file
+// ("gc-percent = 80" lines, # starts a comment) and, upper-cased with HLC_ prefix
+// and underscores, as the environment variable (HLC_GC_PERCENT).
+// Flags override the environment, the environment overrides the file.
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is the prefix of the environment variables
+const EnvPrefix = "HLC_"
+
+// ErrFileSyntax is returned for config file lines that are not "name = value"
+var ErrFileSyntax = errors.New("Config file line should be name = value")
+
+// Config is the effective configuration of the server
+type Config struct {
+	// data sources
+	Data          string
+	DataSource    string
+	DataFormat    string
+	OptionsFile   string
+	ImportWorkers int
+	Strict        bool
+	Watch         string
+	WatchInterval time.Duration
+	ExportPage    int
+	Snapshot      string
+	WAL           string
+	WALSync       string
+	WALInterval   time.Duration
+	Cascade       string
+
+	// replication
+	Leader       string
+	EventsBuffer int
+
+	// clock of the age filters
+	Clock     string
+	ClockTime string
+	Timezone  string
+
+	// server
+	Listen    string
+	GCPercent int
+	LogLevel  string
+	Limits    Limits
+
+	// File is the config file, PrintOnly is set by -print-config,
+	// Export is the archive written by -export
+	File      string
+	PrintOnly bool
+	Export    string
+
+	// setSources keeps where the settings that are not defaults came from
+	setSources map[string]string
+	fs         *flag.FlagSet
+}
+
+// Limits of the HTTP server, zero values keep the defaults of fasthttp
+type Limits struct {
+	Concurrency          int
+	MaxConnsPerIP        int
+	MaxRequestBodySize   int
+	ReadTimeout          time.Duration
+	WriteTimeout         time.Duration
+	MaxKeepaliveDuration time.Duration
+	MaxBatchSize         int
+}
+
+// flags that are not settings
+const (
+	configFlag      = "config"
+	printConfigFlag = "print-config"
+	exportFlag      = "export"
+)
+
+func newFlagSet(c *Config, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&c.Data, "data", "/tmp/data/data.zip", "initial data: a directory, a zip, tar or tar.gz archive")
+	fs.StringVar(&c.DataSource, "data-source", "", "kind of the initial data: dir, zip, tar or file (empty to detect it by the path)")
+	fs.StringVar(&c.DataFormat, "data-format", "", "format of the data files: json or ndjson (empty to detect it by the extensions)")
+	fs.StringVar(&c.OptionsFile, "options", "/tmp/data/options.txt", "file with the current timestamp of the data")
+	fs.IntVar(&c.ExportPage, "export-page-size", 10000, "number of the records in a file of the exported archive")
+	fs.IntVar(&c.ImportWorkers, "import-workers", 0, "number of the data files imported concurrently (0 for GOMAXPROCS)")
+	fs.BoolVar(&c.Strict, "strict", false, "abort the startup if any imported record is rejected")
+	fs.StringVar(&c.Watch, "watch", "", "directory of the data files and archives merged into the running store (empty to disable, requires the WAL or the snapshot)")
+	fs.DurationVar(&c.WatchInterval, "watch-interval", time.Second, "poll interval of the watched directory")
+	fs.StringVar(&c.Snapshot, "snapshot", "", "store snapshot file (empty to disable snapshots)")
+	fs.StringVar(&c.WAL, "wal", "", "write-ahead log file (empty to disable the log)")
+	fs.StringVar(&c.WALSync, "wal-sync", "batch", "WAL fsync policy: always, batch or none")
+	fs.DurationVar(&c.WALInterval, "wal-sync-interval", 100*time.Millisecond, "WAL fsync interval for the batch policy")
+	fs.StringVar(&c.Cascade, "cascade", "reject", "policy for visits of deleted users and locations: reject, cascade or orphan")
+
+	fs.StringVar(&c.Leader, "leader", "", "address of the leader to follow (the node is a read-only follower then)")
+	fs.IntVar(&c.EventsBuffer, "events-buffer", 10000, "number of the last store events kept for /events subscribers (0 disables events)")
+
+	fs.StringVar(&c.Clock, "clock", "options", "clock of the age filters: options (the time of the options file), fixed (clock-time) or wall")
+	fs.StringVar(&c.ClockTime, "clock-time", "", "time of the fixed clock: unix seconds or RFC3339")
+	fs.StringVar(&c.Timezone, "timezone", "UTC", "time zone of the birthdays of the age filters")
+
+	fs.StringVar(&c.Listen, "listen", ":80", "HTTP listen address")
+	fs.IntVar(&c.GCPercent, "gc-percent", 80, "garbage collection target percentage (negative disables GC)")
+	fs.StringVar(&c.LogLevel, "log-level", "info", "log level: debug, info, warning, error, fatal or panic")
+	fs.IntVar(&c.Limits.Concurrency, "concurrency", 0, "maximum number of the concurrent connections (0 for the fasthttp default)")
+	fs.IntVar(&c.Limits.MaxConnsPerIP, "max-conns-per-ip", 0, "maximum number of the connections per client IP (0 for no limit)")
+	fs.IntVar(&c.Limits.MaxRequestBodySize, "max-request-body-size", 0, "maximum request body size in bytes (0 for the fasthttp default)")
+	fs.DurationVar(&c.Limits.ReadTimeout, "read-timeout", 0, "timeout of reading a request (0 for no timeout)")
+	fs.DurationVar(&c.Limits.WriteTimeout, "write-timeout", 0, "timeout of writing a response, it limits /events streams too (0 for no timeout)")
+	fs.DurationVar(&c.Limits.MaxKeepaliveDuration, "max-keepalive-duration", 0, "maximum lifetime of a keep-alive connection (0 for no limit)")
+	fs.IntVar(&c.Limits.MaxBatchSize, "max-batch-size", 10000, "maximum number of operations in POST /batch")
+
+	fs.StringVar(&c.File, configFlag, "", "optional config file of name = value lines (HLC_CONFIG)")
+	fs.BoolVar(&c.PrintOnly, printConfigFlag, false, "print the effective configuration and exit")
+	fs.StringVar(&c.Export, exportFlag, "", "write the loaded data into the zip archive and exit")
+	return fs
+}
+
+// Load builds the configuration from args (without the program name), the environment and the config file
+func Load(name string, args []string, env []string) (*Config, error) {
+	c := &Config{setSources: make(map[string]string)}
+	c.fs = newFlagSet(c, name)
+	if err := c.fs.Parse(args); err != nil {
+		return nil, err
+	}
+	c.fs.Visit(func(f *flag.Flag) {
+		c.setSources[f.Name] = "flag"
+	})
+
+	vars := envMap(env)
+	if c.File == "" {
+		c.File = vars[envName(configFlag)]
+	}
+	if c.File != "" {
+		if err := c.loadFile(c.File); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	c.fs.VisitAll(func(f *flag.Flag) {
+		value, ok := vars[envName(f.Name)]
+		if !ok || err != nil || isCommand(f.Name) || c.setSources[f.Name] == "flag" {
+			return
+		}
+		if errSet := c.fs.Set(f.Name, value); errSet != nil {
+			err = fmt.Errorf("%s: %v", envName(f.Name), errSet)
+			return
+		}
+		c.setSources[f.Name] = "env"
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadFile sets the values of the file that are not set by the flags,
+// the environment is applied afterwards and overrides them
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		i := strings.Index(text, "=")
+		if i < 0 {
+			return fmt.Errorf("%s:%d: %v", path, line, ErrFileSyntax)
+		}
+		name, value := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		if isCommand(name) || c.fs.Lookup(name) == nil {
+			return fmt.Errorf("%s:%d: unknown setting %s", path, line, name)
+		}
+		if c.setSources[name] == "flag" {
+			continue
+		}
+		if err := c.fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		c.setSources[name] = "file"
+	}
+	return scanner.Err()
+}
+
+// Print writes the effective settings in the config file format with their sources
+func (c *Config) Print(w io.Writer) error {
+	var err error
+	c.fs.VisitAll(func(f *flag.Flag) {
+		if isCommand(f.Name) || err != nil {
+			return
+		}
+		source := c.setSources[f.Name]
+		if source == "" {
+			source = "default"
+		}
+		_, err = fmt.Fprintf(w, "%s = %s # %s\n", f.Name, f.Value, source)
+	})
+	return err
+}
+
+func isCommand(name string) bool {
+	return name == configFlag || name == printConfigFlag || name == exportFlag
+}
+
+func envName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+func envMap(env []string) map[string]string {
+	vars := make(map[string]string)
+	for _, kv := range env {
+		if i := strings.Index(kv, "="); i > 0 && strings.HasPrefix(kv, EnvPrefix) {
+			vars[kv[:i]] = kv[i+1:]
+		}
+	}
+	return vars
+}
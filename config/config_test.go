@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, text string) string {
+	f, err := ioutil.TempFile("", "hlc-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeFile(t, `
+# data sources
+data = /data/file.zip
+listen = :8000 # overridden by the env
+gc-percent = 50
+read-timeout = 2s
+log-level = debug
+`)
+	defer os.Remove(file)
+	args := []string{"-gc-percent", "100"}
+	env := []string{"HLC_CONFIG=" + file, "HLC_LISTEN=:9000", "HLC_GC_PERCENT=200", "PATH=/bin"}
+	c, err := Load("hlc", args, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.File != file {
+		t.Errorf("expected the config file of HLC_CONFIG, got %q", c.File)
+	}
+	if c.GCPercent != 100 {
+		t.Errorf("expected gc-percent of the flag, got %d", c.GCPercent)
+	}
+	if c.Listen != ":9000" {
+		t.Errorf("expected listen of the env, got %s", c.Listen)
+	}
+	if c.Data != "/data/file.zip" || c.LogLevel != "debug" || c.Limits.ReadTimeout != 2*time.Second {
+		t.Errorf("expected the settings of the file, got %+v", c)
+	}
+	if c.Snapshot != "" || c.Limits.MaxBatchSize != 10000 {
+		t.Errorf("expected the defaults, got %+v", c)
+	}
+
+	var b bytes.Buffer
+	if err := c.Print(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, line := range []string{
+		"gc-percent = 100 # flag\n",
+		"listen = :9000 # env\n",
+		"data = /data/file.zip # file\n",
+		"read-timeout = 2s # file\n",
+		"max-batch-size = 10000 # default\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("expected %q in the printed config:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "print-config") {
+		t.Errorf("command flags are printed:\n%s", out)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	cases := []struct {
+		text string
+		err  string
+	}{
+		{"listen :80", ErrFileSyntax.Error()},
+		{"unknown = 1", "unknown setting unknown"},
+		{"print-config = true", "unknown setting print-config"},
+		{"\n\ngc-percent = x", ":3: "},
+	}
+	for _, c := range cases {
+		file := writeFile(t, c.text)
+		_, err := Load("hlc", []string{"-config", file}, nil)
+		os.Remove(file)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%q: expected error %q, got %v", c.text, c.err, err)
+		}
+	}
+
+	if _, err := Load("hlc", nil, []string{"HLC_READ_TIMEOUT=1"}); err == nil || !strings.Contains(err.Error(), "HLC_READ_TIMEOUT") {
+		t.Errorf("expected the error of HLC_READ_TIMEOUT, got %v", err)
+	}
+	if _, err := Load("hlc", []string{"-config", "/nonexistent/hlc.conf"}, nil); err == nil {
+		t.Error("missing config file is ignored")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/la0rg/highloadcup/store"
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	ErrArgument          = errors.New("Invalid query argument")
+	ErrRoute             = errors.New("Route does not exist")
+	ErrMethod            = errors.New("Method is not allowed for the route")
+	ErrSnapshotsDisabled = errors.New("Snapshots are disabled")
+	ErrNoImportReport    = errors.New("Data is not imported from the archive")
+	ErrRejectedRecords   = errors.New("Imported records are rejected in strict mode")
+	ErrWatchNotDurable   = errors.New("Watch mode requires the WAL or the snapshot to keep the merged data")
+)
+
+// ErrorDetailsHeader enables the list of all the invalid fields in the error body
+const ErrorDetailsHeader = "X-Error-Details"
+
+// Error codes of the error body
+const (
+	CodeInvalidJSON      = "invalid_json"
+	CodeInvalidArgument  = "invalid_argument"
+	CodeRequiredField    = "required_field"
+	CodeNullValue        = "null_value"
+	CodeInvalidEmail     = "invalid_email"
+	CodeInvalidGender    = "invalid_gender"
+	CodeInvalidMark      = "invalid_mark"
+	CodeInvalidBirthDate = "invalid_birth_date"
+	CodeTooLong          = "too_long"
+	CodeIDInUpdate       = "id_in_update"
+	CodeInvalidID        = "invalid_id"
+	CodeAlreadyExists    = "already_exists"
+	CodeNotFound         = "not_found"
+	CodeHasVisits        = "has_visits"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInvalidOperation = "invalid_operation"
+	CodeAborted          = "aborted"
+	CodeBatchTooLarge    = "batch_too_large"
+	CodeEventsLost       = "events_lost"
+	CodeReadOnly         = "read_only"
+	CodeInternal         = "internal"
+)
+
+// argError is the error of the query argument with the name
+func argError(name string) error {
+	return &model.FieldError{Field: name, Err: ErrArgument}
+}
+
+// writeStoreError writes the error of a store mutation with errorStatus
+func writeStoreError(ctx *fasthttp.RequestCtx, err error) {
+	writeError(ctx, errorStatus(err), err)
+}
+
+// errorStatus is the status of the failed store mutation
+// entity is not found - 404
+// entity has visits - 409
+// incorrect request - 400
+// failed to persist - 500
+func errorStatus(err error) int {
+	switch errorCode(err) {
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeHasVisits, CodeAborted:
+		return http.StatusConflict
+	case CodeInternal:
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// writeError sets the status and writes {"error": {"code", "message", "field"}} body
+// all the invalid fields are listed in "details" if the request has X-Error-Details header
+func writeError(ctx *fasthttp.RequestCtx, status int, err error) {
+	b, errMarshal := easyjson.Marshal(model.ErrorResponse{Error: *newError(ctx, err)})
+	if errMarshal != nil {
+		ctx.Error(errMarshal.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx.Response.Header.Set("Content-Type", "application/json")
+	ctx.SetStatusCode(status)
+	ctx.SetBody(b)
+}
+
+// newError describes err for the error body
+func newError(ctx *fasthttp.RequestCtx, err error) *model.Error {
+	e := model.Error{
+		Code:    errorCode(err),
+		Message: err.Error(),
+		Field:   errorField(err),
+	}
+	if len(ctx.Request.Header.Peek(ErrorDetailsHeader)) > 0 {
+		e.Details = errorDetails(err)
+	}
+	return &e
+}
+
+func errorCode(err error) string {
+	switch e := err.(type) {
+	case model.FieldErrors:
+		return errorCode(e[0])
+	case *model.FieldError:
+		return errorCode(e.Err)
+	case *jlexer.LexerError:
+		return CodeInvalidJSON
+	}
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF:
+		return CodeInvalidJSON
+	case ErrArgument:
+		return CodeInvalidArgument
+	case store.ErrRequiredFields:
+		return CodeRequiredField
+	case model.ErrNullField:
+		return CodeNullValue
+	case model.ErrInvalidEmail:
+		return CodeInvalidEmail
+	case model.ErrInvalidGender:
+		return CodeInvalidGender
+	case model.ErrInvalidMark:
+		return CodeInvalidMark
+	case model.ErrInvalidBirthDate:
+		return CodeInvalidBirthDate
+	case model.ErrTooLong:
+		return CodeTooLong
+	case store.ErrIDInUpdate:
+		return CodeIDInUpdate
+	case store.ErrInvalidID, ErrParse:
+		return CodeInvalidID
+	case store.ErrAlreadyExist:
+		return CodeAlreadyExists
+	case store.ErrDoesNotExist, ErrRoute, ErrSnapshotsDisabled, store.ErrEventsDisabled, ErrNoImportReport:
+		return CodeNotFound
+	case store.ErrHasVisits:
+		return CodeHasVisits
+	case ErrMethod:
+		return CodeMethodNotAllowed
+	case ErrBatchOperation, ErrBatchEntity:
+		return CodeInvalidOperation
+	case store.ErrBatchAborted:
+		return CodeAborted
+	case ErrBatchTooLarge:
+		return CodeBatchTooLarge
+	case store.ErrEventsLost:
+		return CodeEventsLost
+	case ErrReadOnly:
+		return CodeReadOnly
+	}
+	return CodeInternal
+}
+
+func errorField(err error) string {
+	switch e := err.(type) {
+	case model.FieldErrors:
+		return e[0].Field
+	case *model.FieldError:
+		return e.Field
+	}
+	switch err {
+	case store.ErrIDInUpdate, store.ErrInvalidID, store.ErrAlreadyExist:
+		return "id"
+	}
+	return ""
+}
+
+func errorDetails(err error) []model.ErrorDetail {
+	var errs model.FieldErrors
+	switch e := err.(type) {
+	case model.FieldErrors:
+		errs = e
+	case *model.FieldError:
+		errs = model.FieldErrors{e}
+	default:
+		field := errorField(err)
+		if field == "" {
+			return nil
+		}
+		errs = model.FieldErrors{{Field: field, Err: err}}
+	}
+	details := make([]model.ErrorDetail, len(errs))
+	for i, e := range errs {
+		details[i] = model.ErrorDetail{Code: errorCode(e.Err), Message: e.Err.Error(), Field: e.Field}
+	}
+	return details
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/la0rg/highloadcup/config"
+	"github.com/la0rg/highloadcup/model"
+	"github.com/la0rg/highloadcup/store"
+	"github.com/mailru/easyjson"
+	"github.com/valyala/fasthttp"
+)
+
+// newTestHandler sets up the default config and a new store with events
+// and returns the handler of the server
+func newTestHandler(t *testing.T) fasthttp.RequestHandler {
+	c, err := config.Load("highloadcup", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = c
+	dataStore = store.NewStore()
+	dataStore.EnableEvents(cfg.EventsBuffer)
+	following = nil
+	return serve(newRouter())
+}
+
+// do passes the request to the handler, header is the list of names and values
+func do(handler fasthttp.RequestHandler, method, uri, body string, header ...string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	req.SetBodyString(body)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&req, nil, nil)
+	handler(&ctx)
+	return &ctx
+}
+
+// mustDo fails the test if the request is not accepted
+func mustDo(t *testing.T, handler fasthttp.RequestHandler, method, uri, body string) {
+	ctx := do(handler, method, uri, body)
+	if status := ctx.Response.StatusCode(); status != http.StatusOK {
+		t.Fatalf("%s %s %s: status %d, %s", method, uri, body, status, ctx.Response.Body())
+	}
+}
+
+func errorBody(t *testing.T, ctx *fasthttp.RequestCtx) model.Error {
+	var body model.ErrorResponse
+	if err := easyjson.Unmarshal(ctx.Response.Body(), &body); err != nil {
+		t.Fatalf("%s: %v", ctx.Response.Body(), err)
+	}
+	if ct := string(ctx.Response.Header.ContentType()); ct != "application/json" {
+		t.Errorf("expected json error body, got %s", ct)
+	}
+	return body.Error
+}
+
+func TestErrors(t *testing.T) {
+	handler := newTestHandler(t)
+	mustDo(t, handler, "POST", "/users/new", `{"id": 1, "email": "a@b.c", "first_name": "a", "last_name": "b", "gender": "m", "birth_date": 0}`)
+	mustDo(t, handler, "POST", "/locations/new", `{"id": 1, "place": "p", "country": "c", "city": "c", "distance": 1}`)
+	mustDo(t, handler, "POST", "/visits/new", `{"id": 1, "user": 1, "location": 1, "visited_at": 1, "mark": 3}`)
+
+	tests := []struct {
+		method, uri, body string
+		status            int
+		code, field       string
+	}{
+		{"POST", "/users/1", `{"first_name": `, http.StatusBadRequest, CodeInvalidJSON, ""},
+		{"GET", "/users/1/visits?fromDate=x", "", http.StatusBadRequest, CodeInvalidArgument, "fromDate"},
+		{"POST", "/users/new", `{"id": 2, "email": "a@b.c"}`, http.StatusBadRequest, CodeRequiredField, "first_name"},
+		{"POST", "/users/1", `{"first_name": null}`, http.StatusBadRequest, CodeNullValue, "first_name"},
+		{"POST", "/users/1", `{"email": "x"}`, http.StatusBadRequest, CodeInvalidEmail, "email"},
+		{"POST", "/users/1", `{"gender": "x"}`, http.StatusBadRequest, CodeInvalidGender, "gender"},
+		{"POST", "/visits/1", `{"mark": 6}`, http.StatusBadRequest, CodeInvalidMark, "mark"},
+		{"POST", "/users/1", `{"id": 2}`, http.StatusBadRequest, CodeIDInUpdate, "id"},
+		{"POST", "/users/new", `{"id": 1, "email": "a@b.c", "first_name": "a", "last_name": "b", "gender": "m", "birth_date": 0}`,
+			http.StatusBadRequest, CodeAlreadyExists, "id"},
+		{"GET", "/users/2", "", http.StatusNotFound, CodeNotFound, ""},
+		{"POST", "/users/2", `{"first_name": `, http.StatusNotFound, CodeNotFound, ""},
+		{"GET", "/sights/1", "", http.StatusNotFound, CodeNotFound, ""},
+		{"POST", "/admin/snapshot", "", http.StatusNotFound, CodeNotFound, ""},
+		{"DELETE", "/users/1", "", http.StatusConflict, CodeHasVisits, ""},
+		{"PUT", "/users/1", "", http.StatusMethodNotAllowed, CodeMethodNotAllowed, ""},
+	}
+	for _, test := range tests {
+		ctx := do(handler, test.method, test.uri, test.body)
+		if status := ctx.Response.StatusCode(); status != test.status {
+			t.Errorf("%s %s %s: expected status %d, got %d", test.method, test.uri, test.body, test.status, status)
+			continue
+		}
+		e := errorBody(t, ctx)
+		if e.Code != test.code || e.Field != test.field || e.Message == "" || e.Details != nil {
+			t.Errorf("%s %s %s: expected %s error of %q, got %+v", test.method, test.uri, test.body, test.code, test.field, e)
+		}
+	}
+	if u, _ := dataStore.GetUserByID(1); u.Email.V != "a@b.c" || u.Gender.V != "m" {
+		t.Errorf("user is changed by the failed requests: %v", u)
+	}
+}
+
+func TestErrors_Details(t *testing.T) {
+	handler := newTestHandler(t)
+	body := `{"id": 1, "email": "x", "first_name": "a", "last_name": "b", "gender": "x", "birth_date": 0}`
+	ctx := do(handler, "POST", "/users/new", body, ErrorDetailsHeader, "1")
+	if status := ctx.Response.StatusCode(); status != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", status)
+	}
+	e := errorBody(t, ctx)
+	if e.Code != CodeInvalidEmail || e.Field != "email" {
+		t.Errorf("expected the first invalid field, got %+v", e)
+	}
+	expected := []model.ErrorDetail{
+		{Code: CodeInvalidEmail, Message: model.ErrInvalidEmail.Error(), Field: "email"},
+		{Code: CodeInvalidGender, Message: model.ErrInvalidGender.Error(), Field: "gender"},
+	}
+	if !reflect.DeepEqual(e.Details, expected) {
+		t.Errorf("expected details %+v, got %+v", expected, e.Details)
+	}
+
+	ctx = do(handler, "POST", "/users/new", `{"id": 1, "email": "a@b.c"}`, ErrorDetailsHeader, "1")
+	var fields []string
+	for _, d := range errorBody(t, ctx).Details {
+		if d.Code != CodeRequiredField {
+			t.Errorf("expected required_field, got %+v", d)
+		}
+		fields = append(fields, d.Field)
+	}
+	if expected := []string{"first_name", "last_name", "gender", "birth_date"}; !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected the missing fields %v, got %v", expected, fields)
+	}
+
+	// the errors without a field have no details
+	ctx = do(handler, "GET", "/users/1", "", ErrorDetailsHeader, "1")
+	if e := errorBody(t, ctx); e.Code != CodeNotFound || e.Details != nil {
+		t.Errorf("expected not_found without details, got %+v", e)
+	}
+	mustDo(t, handler, "POST", "/users/new", `{"id": 1, "email": "a@b.c", "first_name": "a", "last_name": "b", "gender": "m", "birth_date": 0}`)
+	ctx = do(handler, "POST", "/users/1", `{"id": 2}`, ErrorDetailsHeader, "1")
+	expected = []model.ErrorDetail{{Code: CodeIDInUpdate, Message: store.ErrIDInUpdate.Error(), Field: "id"}}
+	if e := errorBody(t, ctx); !reflect.DeepEqual(e.Details, expected) {
+		t.Errorf("expected details %+v, got %+v", expected, e.Details)
+	}
+}
+
+func TestErrors_EventsAndFollower(t *testing.T) {
+	handler := newTestHandler(t)
+	dataStore.EnableEvents(1)
+	for id := 1; id <= 3; id++ {
+		mustDo(t, handler, "POST", "/locations/new", `{"id": `+strconv.Itoa(id)+`, "place": "p", "country": "c", "city": "c", "distance": 1}`)
+	}
+	ctx := do(handler, "GET", "/events?since=0", "")
+	if status := ctx.Response.StatusCode(); status != http.StatusGone {
+		t.Errorf("expected status 410, got %d", status)
+	}
+	if e := errorBody(t, ctx); e.Code != CodeEventsLost {
+		t.Errorf("expected events_lost, got %+v", e)
+	}
+
+	following = newReplica("127.0.0.1:0", dataStore)
+	defer func() { following = nil }()
+	ctx = do(handler, "DELETE", "/locations/1", "")
+	if status := ctx.Response.StatusCode(); status != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", status)
+	}
+	if e := errorBody(t, ctx); e.Code != CodeReadOnly {
+		t.Errorf("expected read_only, got %+v", e)
+	}
+	if _, ok := dataStore.GetLocationByID(1); !ok {
+		t.Error("follower deleted the location")
+	}
+}
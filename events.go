@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/la0rg/highloadcup/store"
+	"github.com/mailru/easyjson"
+	"github.com/valyala/fasthttp"
+)
+
+// Since is the query argument of GET /events
+const Since = "since"
+
+// lastEventIDHeader is sent by the reconnecting EventSource clients
+const lastEventIDHeader = "Last-Event-ID"
+
+// eventsHeartbeat is the interval of the comments sent to idle subscribers to detect closed connections
+const eventsHeartbeat = 15 * time.Second
+
+// Events streams the store changes as Server-Sent Events, the id of an event is its sequence number
+// since=<seq> (or Last-Event-ID header) starts after the event seq, without it only new events are sent
+// events are disabled - 404
+// events after since are not kept anymore - 410
+func Events(ctx *fasthttp.RequestCtx) {
+	since := dataStore.LastEventSeq()
+	arg := ctx.QueryArgs().Peek(Since)
+	if len(arg) == 0 {
+		arg = ctx.Request.Header.Peek(lastEventIDHeader)
+	}
+	if len(arg) > 0 {
+		seq, err := strconv.ParseUint(string(arg), 10, 64)
+		if err != nil {
+			writeError(ctx, http.StatusBadRequest, argError(Since))
+			return
+		}
+		since = seq
+	}
+	sub, err := dataStore.Subscribe(since)
+	if err != nil {
+		status := http.StatusNotFound
+		if err == store.ErrEventsLost {
+			status = http.StatusGone
+		}
+		writeError(ctx, status, err)
+		return
+	}
+
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.Response.Header.Set(eventEpochHeader, eventEpoch)
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		for {
+			events, err := sub.Next(eventsHeartbeat)
+			if err != nil {
+				// the subscriber is too slow, it should reconnect with the last id to get 410
+				w.WriteString("event: error\ndata: ")
+				w.WriteString(err.Error())
+				w.WriteString("\n\n")
+				w.Flush()
+				return
+			}
+			if len(events) == 0 {
+				w.WriteString(": ping\n\n")
+			}
+			for i := range events {
+				if err := writeEvent(w, &events[i]); err != nil {
+					return
+				}
+			}
+			if w.Flush() != nil {
+				return
+			}
+		}
+	})
+}
+
+// writeEvent writes the event with its sequence number as id
+func writeEvent(w *bufio.Writer, e *store.Event) error {
+	event, err := newEvent(e)
+	if err != nil {
+		return err
+	}
+	b, err := easyjson.Marshal(event)
+	if err != nil {
+		return err
+	}
+	w.WriteString("id: ")
+	w.WriteString(strconv.FormatUint(e.Seq, 10))
+	w.WriteString("\ndata: ")
+	w.Write(b)
+	_, err = w.WriteString("\n\n")
+	return err
+}
+
+func newEvent(e *store.Event) (*model.Event, error) {
+	event := &model.Event{
+		Seq:     e.Seq,
+		Time:    e.Time.UnixNano() / int64(time.Millisecond),
+		Type:    e.Type.String(),
+		Entity:  e.Entity,
+		ID:      e.ID,
+		Changed: e.Changed,
+	}
+	var err error
+	if e.Before != nil {
+		event.Before, err = easyjson.Marshal(e.Before)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e.After != nil {
+		event.After, err = easyjson.Marshal(e.After)
+	}
+	return event, err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/la0rg/highloadcup/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+// Export returns the store as a zip archive of users_N.json, locations_N.json and visits_N.json files,
+// the archive can be used as data.zip of another instance
+func Export(ctx *fasthttp.RequestCtx) {
+	// the archive is built in memory so slow clients do not hold the store locks
+	var b bytes.Buffer
+	err := util.ExportToZip(&b, dataStore, cfg.ExportPage)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	ctx.SetContentType("application/zip")
+	ctx.Response.Header.Set("Content-Disposition", `attachment; filename="data.zip"`)
+	ctx.SetBody(b.Bytes())
+}
+
+// exportData writes the archive of Export into path, the file is replaced only when it is complete
+func exportData(path string) error {
+	start := time.Now()
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = util.ExportToZip(w, dataStore, cfg.ExportPage)
+	if err == nil {
+		err = w.Flush()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	log.Infof("Exported data to %s in %v", path, time.Since(start))
+	return nil
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/la0rg/highloadcup/clock"
+	"github.com/la0rg/highloadcup/router"
 	"github.com/la0rg/highloadcup/store"
 	"github.com/la0rg/highloadcup/util"
 	"github.com/mailru/easyjson"
@@ -24,24 +27,91 @@ const (
 	Gender     = "gender"
 	Country    = "country"
 	ToDistance = "toDistance"
+
+	Limit          = "limit"
+	Offset         = "offset"
+	AfterVisitedAt = "after_visited_at"
+	AfterID        = "after_id"
+	Order          = "order"
+
+	City      = "city"
+	MinVisits = "minVisits"
+	By        = "by"
 )
 
+// defaultTopLimit is the number of the locations returned by TopLocations without limit argument
+const defaultTopLimit = 10
+
+// newRouter registers the handlers of the API
+func newRouter() *router.Router {
+	r := router.New()
+	r.NotFound = NotFound
+	r.MethodNotAllowed = MethodNotAllowed
+
+	r.GET("/users/{id:int}", User)
+	r.GET("/users/{id:int}/visits", VisitsByUser)
+	r.GET("/users/{id:int}/summary", UserSummary)
+	r.POST("/users/new", UserCreate)
+	r.POST("/users/{id:int}", UserUpdate)
+	r.DELETE("/users/{id:int}", UserDelete)
+
+	r.GET("/locations/top", TopLocations)
+	r.GET("/locations/{id:int}", Location)
+	r.GET("/locations/{id:int}/avg", LocationAvg)
+	r.GET("/locations/{id:int}/stats", LocationStats)
+	r.POST("/locations/new", LocationCreate)
+	r.POST("/locations/{id:int}", LocationUpdate)
+	r.DELETE("/locations/{id:int}", LocationDelete)
+
+	r.GET("/visits/{id:int}", Visit)
+	r.POST("/visits/new", VisitCreate)
+	r.POST("/visits/{id:int}", VisitUpdate)
+	r.DELETE("/visits/{id:int}", VisitDelete)
+
+	r.POST("/batch", Batch)
+	r.GET("/events", Events)
+	r.GET("/replication/snapshot", ReplicationSnapshot)
+	r.GET("/replication/status", ReplicationStatus)
+	r.POST("/admin/snapshot", Snapshot)
+	r.GET("/admin/import-report", ImportReport)
+	r.GET("/export", Export)
+	return r
+}
+
 // User returns a user by id
 func User(ctx *fasthttp.RequestCtx) {
 	id, err := parseID(ctx)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusNotFound)
+		writeError(ctx, http.StatusNotFound, err)
 		return
 	}
 	user, ok := dataStore.GetUserByID(id)
 	if ok {
 		err = writeStructAsJSON(ctx, user)
 		if err != nil {
-			ctx.Error(err.Error(), http.StatusInternalServerError)
+			writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
+}
+
+// UserSummary returns aggregated visits of the user
+func UserSummary(ctx *fasthttp.RequestCtx) {
+	id, err := parseID(ctx)
+	if err != nil {
+		writeError(ctx, http.StatusNotFound, err)
+		return
+	}
+	summary, ok := dataStore.GetUserSummary(id)
+	if ok {
+		err = writeStructAsJSON(ctx, summary)
+		if err != nil {
+			writeError(ctx, http.StatusInternalServerError, err)
 		}
 		return
 	}
-	ctx.SetStatusCode(http.StatusNotFound)
+	writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
 }
 
 // UserUpdate update user entity
@@ -51,34 +121,29 @@ func User(ctx *fasthttp.RequestCtx) {
 func UserUpdate(ctx *fasthttp.RequestCtx) {
 	id, err := parseID(ctx)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusNotFound)
+		writeError(ctx, http.StatusNotFound, err)
 		return
 	}
 
-	bytes := ctx.PostBody()
-	errNull := util.ContainsNull(bytes)
-	if errNull {
-		ctx.SetStatusCode(http.StatusBadRequest)
+	var user model.User
+	errParse := user.UnmarshalStrict(ctx.PostBody())
+	// null fields are not allowed even for the missing users
+	if _, null := errParse.(model.FieldErrors); null {
+		writeError(ctx, http.StatusBadRequest, errParse)
 		return
 	}
-
-	var user model.User
-	errParse := easyjson.Unmarshal(bytes, &user)
 	if errParse != nil {
-		user = model.User{}
-	}
-	err = dataStore.UpdateUserByID(id, user)
-	// 404 is a higher priority than 400
-	if err != nil {
-		if err == store.ErrDoesNotExist {
-			ctx.SetStatusCode(http.StatusNotFound)
-		} else {
-			ctx.SetStatusCode(http.StatusBadRequest)
+		// 404 is a higher priority than 400
+		if _, ok := dataStore.GetUserByID(id); !ok {
+			writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
+			return
 		}
+		writeError(ctx, http.StatusBadRequest, errParse)
 		return
 	}
-	if errParse != nil {
-		ctx.SetStatusCode(http.StatusBadRequest)
+	err = dataStore.UpdateUserByID(id, user)
+	if err != nil {
+		writeStoreError(ctx, err)
 		return
 	}
 	ctx.SetBody(emptyObject)
@@ -90,14 +155,32 @@ func UserUpdate(ctx *fasthttp.RequestCtx) {
 // incorrect request - 400
 func UserCreate(ctx *fasthttp.RequestCtx) {
 	var user model.User
-	err := easyjson.Unmarshal(ctx.PostBody(), &user)
+	err := user.UnmarshalStrict(ctx.PostBody())
 	if err != nil {
-		ctx.SetStatusCode(http.StatusBadRequest)
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 	err = dataStore.AddUser(user)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusBadRequest)
+		writeStoreError(ctx, err)
+		return
+	}
+	ctx.SetBody(emptyObject)
+}
+
+// UserDelete delete user entity
+// success - 200 with body {}
+// id is not found - 404
+// has visits and cascade policy is reject - 409
+func UserDelete(ctx *fasthttp.RequestCtx) {
+	id, err := parseID(ctx)
+	if err != nil {
+		writeError(ctx, http.StatusNotFound, err)
+		return
+	}
+	err = dataStore.DeleteUser(id)
+	if err != nil {
+		writeStoreError(ctx, err)
 		return
 	}
 	ctx.SetBody(emptyObject)
@@ -107,7 +190,7 @@ func UserCreate(ctx *fasthttp.RequestCtx) {
 func Location(ctx *fasthttp.RequestCtx) {
 	id, err := parseID(ctx)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusNotFound)
+		writeError(ctx, http.StatusNotFound, err)
 		return
 	}
 
@@ -115,78 +198,124 @@ func Location(ctx *fasthttp.RequestCtx) {
 	if ok {
 		err = writeStructAsJSON(ctx, location)
 		if err != nil {
-			ctx.Error(err.Error(), http.StatusInternalServerError)
+			writeError(ctx, http.StatusInternalServerError, err)
 		}
 		return
 	}
-	ctx.SetStatusCode(http.StatusNotFound)
+	writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
 }
 
-// Location returns a location by id
+// LocationAvg returns the average mark of the location visits
 func LocationAvg(ctx *fasthttp.RequestCtx) {
 	id, err := parseID(ctx)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusNotFound)
+		writeError(ctx, http.StatusNotFound, err)
 		return
 	}
 
-	var fromDate, toDate, fromAge, toAge *int64
-	var gender *string
+	f, err := parseLocationFilter(ctx.QueryArgs(), clk.Now(), ageZone)
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
 
-	args := ctx.QueryArgs()
-	if args.Has(FromDate) {
-		i64, err := strconv.ParseInt(string(args.Peek(FromDate)), 10, 64)
+	avg, ok := dataStore.GetLocationAvg(id, f.fromDate, f.toDate, f.fromAge, f.toAge, f.gender)
+	if ok {
+		//avg = util.RoundPlus(avg, 5)
+		err = writeStructAsJSON(ctx, model.Avg{Value: avg})
 		if err != nil {
-			ctx.SetStatusCode(http.StatusBadRequest)
-			return
+			writeError(ctx, http.StatusInternalServerError, err)
 		}
-		fromDate = &i64
+		return
 	}
-	if args.Has(ToDate) {
-		i64, err := strconv.ParseInt(string(args.Peek(ToDate)), 10, 64)
+	writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
+}
+
+// LocationStats returns the distribution of the marks of the location visits
+// filters are the same as in LocationAvg
+func LocationStats(ctx *fasthttp.RequestCtx) {
+	id, err := parseID(ctx)
+	if err != nil {
+		writeError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	f, err := parseLocationFilter(ctx.QueryArgs(), clk.Now(), ageZone)
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	stats, ok := dataStore.GetLocationStats(id, f.fromDate, f.toDate, f.fromAge, f.toAge, f.gender)
+	if ok {
+		err = writeStructAsJSON(ctx, stats)
 		if err != nil {
-			ctx.SetStatusCode(http.StatusBadRequest)
+			writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
+}
+
+// TopLocations returns locations with the best average mark (or the most visited with by=count)
+// filters are the same as in LocationAvg plus country, city, minVisits and limit
+func TopLocations(ctx *fasthttp.RequestCtx) {
+	args := ctx.QueryArgs()
+	f, err := parseLocationFilter(args, clk.Now(), ageZone)
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	top := store.TopFilter{
+		FromDate:  f.fromDate,
+		ToDate:    f.toDate,
+		FromAge:   f.fromAge,
+		ToAge:     f.toAge,
+		Gender:    f.gender,
+		MinVisits: 1,
+		Limit:     defaultTopLimit,
+	}
+	if args.Has(Country) {
+		str := string(args.Peek(Country))
+		top.Country = &str
+		if !util.OnlyLetters(str) {
+			writeError(ctx, http.StatusBadRequest, argError(Country))
 			return
 		}
-		toDate = &i64
 	}
-	if args.Has(FromAge) {
-		i, err := strconv.Atoi(string(args.Peek(FromAge)))
-		if err != nil {
-			ctx.SetStatusCode(http.StatusBadRequest)
+	if args.Has(City) {
+		str := string(args.Peek(City))
+		top.City = &str
+	}
+	if args.Has(MinVisits) {
+		top.MinVisits, err = strconv.Atoi(string(args.Peek(MinVisits)))
+		if err != nil || top.MinVisits < 0 {
+			writeError(ctx, http.StatusBadRequest, argError(MinVisits))
 			return
 		}
-		date := now.AddDate(-i, 0, 0).Unix()
-		fromAge = &date
 	}
-	if args.Has(ToAge) {
-		i, err := strconv.Atoi(string(args.Peek(ToAge)))
-		if err != nil {
-			ctx.SetStatusCode(http.StatusBadRequest)
+	if args.Has(Limit) {
+		top.Limit, err = strconv.Atoi(string(args.Peek(Limit)))
+		if err != nil || top.Limit < 0 {
+			writeError(ctx, http.StatusBadRequest, argError(Limit))
 			return
 		}
-		date := now.AddDate(-i, 0, 0).Unix()
-		toAge = &date
 	}
-	if args.Has(Gender) {
-		str := string(args.Peek(Gender))
-		gender = &str
-		if !util.IsGender(*gender) {
-			ctx.SetStatusCode(http.StatusBadRequest)
+	if args.Has(By) {
+		switch string(args.Peek(By)) {
+		case "avg":
+		case "count":
+			top.ByVisits = true
+		default:
+			writeError(ctx, http.StatusBadRequest, argError(By))
 			return
 		}
 	}
 
-	avg, ok := dataStore.GetLocationAvg(id, fromDate, toDate, fromAge, toAge, gender)
-	if ok {
-		//avg = util.RoundPlus(avg, 5)
-		err = writeStructAsJSON(ctx, model.Avg{Value: avg})
-		if err != nil {
-			ctx.Error(err.Error(), http.StatusInternalServerError)
-		}
-		return
+	err = writeStructAsJSON(ctx, dataStore.GetTopLocations(top))
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
 	}
-	ctx.SetStatusCode(http.StatusNotFound)
 }
 
 // LocationUpdate update location entity
@@ -196,34 +325,29 @@ func LocationAvg(ctx *fasthttp.RequestCtx) {
 func LocationUpdate(ctx *fasthttp.RequestCtx) {
 	id, err := parseID(ctx)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusNotFound)
+		writeError(ctx, http.StatusNotFound, err)
 		return
 	}
 
-	bytes := ctx.PostBody()
-	errNull := util.ContainsNull(bytes)
-	if errNull {
-		ctx.SetStatusCode(http.StatusBadRequest)
+	var location model.Location
+	errParse := location.UnmarshalStrict(ctx.PostBody())
+	// null fields are not allowed even for the missing locations
+	if _, null := errParse.(model.FieldErrors); null {
+		writeError(ctx, http.StatusBadRequest, errParse)
 		return
 	}
-
-	var location model.Location
-	errParse := easyjson.Unmarshal(bytes, &location)
 	if errParse != nil {
-		location = model.Location{}
-	}
-
-	err = dataStore.UpdateLocationByID(id, location)
-	if err != nil {
-		if err == store.ErrDoesNotExist {
-			ctx.SetStatusCode(http.StatusNotFound)
-		} else {
-			ctx.SetStatusCode(http.StatusBadRequest)
+		// 404 is a higher priority than 400
+		if _, ok := dataStore.GetLocationByID(id); !ok {
+			writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
+			return
 		}
+		writeError(ctx, http.StatusBadRequest, errParse)
 		return
 	}
-	if errParse != nil {
-		ctx.SetStatusCode(http.StatusBadRequest)
+	err = dataStore.UpdateLocationByID(id, location)
+	if err != nil {
+		writeStoreError(ctx, err)
 		return
 	}
 	ctx.SetBody(emptyObject)
@@ -234,17 +358,33 @@ func LocationUpdate(ctx *fasthttp.RequestCtx) {
 // already exist - 400
 // incorrect request - 400
 func LocationCreate(ctx *fasthttp.RequestCtx) {
-	bytes := ctx.PostBody()
-
 	var location model.Location
-	err := easyjson.Unmarshal(bytes, &location)
+	err := location.UnmarshalStrict(ctx.PostBody())
 	if err != nil {
-		ctx.SetStatusCode(http.StatusBadRequest)
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 	err = dataStore.AddLocation(location)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusBadRequest)
+		writeStoreError(ctx, err)
+		return
+	}
+	ctx.SetBody(emptyObject)
+}
+
+// LocationDelete delete location entity
+// success - 200 with body {}
+// id is not found - 404
+// has visits and cascade policy is reject - 409
+func LocationDelete(ctx *fasthttp.RequestCtx) {
+	id, err := parseID(ctx)
+	if err != nil {
+		writeError(ctx, http.StatusNotFound, err)
+		return
+	}
+	err = dataStore.DeleteLocation(id)
+	if err != nil {
+		writeStoreError(ctx, err)
 		return
 	}
 	ctx.SetBody(emptyObject)
@@ -254,24 +394,24 @@ func LocationCreate(ctx *fasthttp.RequestCtx) {
 func Visit(ctx *fasthttp.RequestCtx) {
 	id, err := parseID(ctx)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusNotFound)
+		writeError(ctx, http.StatusNotFound, err)
 		return
 	}
 	visit, ok := dataStore.GetVisitByID(id)
 	if ok {
 		err = writeStructAsJSON(ctx, visit)
 		if err != nil {
-			ctx.Error(err.Error(), http.StatusInternalServerError)
+			writeError(ctx, http.StatusInternalServerError, err)
 		}
 		return
 	}
-	ctx.SetStatusCode(http.StatusNotFound)
+	writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
 }
 
 func VisitsByUser(ctx *fasthttp.RequestCtx) {
 	id, err := parseID(ctx)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusNotFound)
+		writeError(ctx, http.StatusNotFound, err)
 		return
 	}
 
@@ -282,7 +422,7 @@ func VisitsByUser(ctx *fasthttp.RequestCtx) {
 	if args.Has(FromDate) {
 		i64, err := strconv.ParseInt(string(args.Peek(FromDate)), 10, 64)
 		if err != nil {
-			ctx.SetStatusCode(http.StatusBadRequest)
+			writeError(ctx, http.StatusBadRequest, argError(FromDate))
 			return
 		}
 		fromDate = &i64
@@ -290,7 +430,7 @@ func VisitsByUser(ctx *fasthttp.RequestCtx) {
 	if args.Has(ToDate) {
 		i64, err := strconv.ParseInt(string(args.Peek(ToDate)), 10, 64)
 		if err != nil {
-			ctx.SetStatusCode(http.StatusBadRequest)
+			writeError(ctx, http.StatusBadRequest, argError(ToDate))
 			return
 		}
 		toDate = &i64
@@ -299,29 +439,35 @@ func VisitsByUser(ctx *fasthttp.RequestCtx) {
 		str := string(args.Peek(Country))
 		country = &(str)
 		if !util.OnlyLetters(*country) {
-			ctx.SetStatusCode(http.StatusBadRequest)
+			writeError(ctx, http.StatusBadRequest, argError(Country))
 			return
 		}
 	}
 	if args.Has(ToDistance) {
 		i64, err := strconv.ParseInt(string(args.Peek(ToDistance)), 10, 32)
 		if err != nil {
-			ctx.SetStatusCode(http.StatusBadRequest)
+			writeError(ctx, http.StatusBadRequest, argError(ToDistance))
 			return
 		}
 		i32 := int32(i64)
 		toDistance = &i32
 	}
 
-	visits, ok := dataStore.GetVisitsByUserID(id, fromDate, toDate, country, toDistance)
+	page, err := parsePage(args)
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	visits, ok := dataStore.GetVisitsByUserID(id, fromDate, toDate, country, toDistance, page)
 	if ok {
 		err := writeStructAsJSON(ctx, visits)
 		if err != nil {
-			ctx.Error(err.Error(), http.StatusInternalServerError)
+			writeError(ctx, http.StatusInternalServerError, err)
 		}
 		return
 	}
-	ctx.SetStatusCode(http.StatusNotFound)
+	writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
 }
 
 // VisitUpdate update user entity
@@ -331,34 +477,29 @@ func VisitsByUser(ctx *fasthttp.RequestCtx) {
 func VisitUpdate(ctx *fasthttp.RequestCtx) {
 	id, err := parseID(ctx)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusNotFound)
+		writeError(ctx, http.StatusNotFound, err)
 		return
 	}
 
-	bytes := ctx.PostBody()
-	errNull := util.ContainsNull(bytes)
-	if errNull {
-		ctx.SetStatusCode(http.StatusBadRequest)
+	var visit model.Visit
+	errParse := visit.UnmarshalStrict(ctx.PostBody())
+	// null fields are not allowed even for the missing visits
+	if _, null := errParse.(model.FieldErrors); null {
+		writeError(ctx, http.StatusBadRequest, errParse)
 		return
 	}
-
-	var visit model.Visit
-	errParse := easyjson.Unmarshal(bytes, &visit)
 	if errParse != nil {
-		visit = model.Visit{}
-	}
-	err = dataStore.UpdateVisitByID(id, visit)
-	// 404 is a higher priority than 400
-	if err != nil {
-		if err == store.ErrDoesNotExist {
-			ctx.SetStatusCode(http.StatusNotFound)
-		} else {
-			ctx.SetStatusCode(http.StatusBadRequest)
+		// 404 is a higher priority than 400
+		if _, ok := dataStore.GetVisitByID(id); !ok {
+			writeError(ctx, http.StatusNotFound, store.ErrDoesNotExist)
+			return
 		}
+		writeError(ctx, http.StatusBadRequest, errParse)
 		return
 	}
-	if errParse != nil {
-		ctx.SetStatusCode(http.StatusBadRequest)
+	err = dataStore.UpdateVisitByID(id, visit)
+	if err != nil {
+		writeStoreError(ctx, err)
 		return
 	}
 	ctx.SetBody(emptyObject)
@@ -369,32 +510,184 @@ func VisitUpdate(ctx *fasthttp.RequestCtx) {
 // already exist - 400
 // incorrect request - 400
 func VisitCreate(ctx *fasthttp.RequestCtx) {
-	bytes := ctx.PostBody()
-
 	var visit model.Visit
-	err := easyjson.Unmarshal(bytes, &visit)
+	err := visit.UnmarshalStrict(ctx.PostBody())
 	if err != nil {
-		ctx.SetStatusCode(http.StatusBadRequest)
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 	err = dataStore.AddVisit(visit)
 	if err != nil {
-		ctx.SetStatusCode(http.StatusBadRequest)
+		writeStoreError(ctx, err)
+		return
+	}
+	ctx.SetBody(emptyObject)
+}
+
+// VisitDelete delete visit entity
+// success - 200 with body {}
+// id is not found - 404
+func VisitDelete(ctx *fasthttp.RequestCtx) {
+	id, err := parseID(ctx)
+	if err != nil {
+		writeError(ctx, http.StatusNotFound, err)
+		return
+	}
+	err = dataStore.DeleteVisit(id)
+	if err != nil {
+		writeStoreError(ctx, err)
+		return
+	}
+	ctx.SetBody(emptyObject)
+}
+
+// Snapshot saves the store to the snapshot file
+// success - 200 with body {}
+// snapshots are disabled - 404
+func Snapshot(ctx *fasthttp.RequestCtx) {
+	if cfg.Snapshot == "" {
+		writeError(ctx, http.StatusNotFound, ErrSnapshotsDisabled)
+		return
+	}
+	err := saveSnapshot()
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 	ctx.SetBody(emptyObject)
 }
 
+// ImportReport returns the accepted and rejected records of every file of the imported data archive
+// data is loaded from the snapshot or the leader - 404
+func ImportReport(ctx *fasthttp.RequestCtx) {
+	if importReport == nil {
+		writeError(ctx, http.StatusNotFound, ErrNoImportReport)
+		return
+	}
+	err := writeStructAsJSON(ctx, importReport)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
 // NotFound custom request handler for non-found requests
 func NotFound(ctx *fasthttp.RequestCtx) {
-	ctx.SetStatusCode(http.StatusNotFound)
+	writeError(ctx, http.StatusNotFound, ErrRoute)
 }
 
+// MethodNotAllowed custom request handler for the routes without the request method
+func MethodNotAllowed(ctx *fasthttp.RequestCtx) {
+	writeError(ctx, http.StatusMethodNotAllowed, ErrMethod)
+}
+
+// locationFilter keeps the query arguments of the location aggregations
+type locationFilter struct {
+	fromDate, toDate, fromAge, toAge *int64
+	gender                           *string
+}
+
+// parseLocationFilter reads fromDate, toDate, fromAge, toAge and gender arguments,
+// the ages are converted to the birth date boundaries at now in the time zone loc
+func parseLocationFilter(args *fasthttp.Args, now time.Time, loc *time.Location) (*locationFilter, error) {
+	var f locationFilter
+	if args.Has(FromDate) {
+		i64, err := strconv.ParseInt(string(args.Peek(FromDate)), 10, 64)
+		if err != nil {
+			return nil, argError(FromDate)
+		}
+		f.fromDate = &i64
+	}
+	if args.Has(ToDate) {
+		i64, err := strconv.ParseInt(string(args.Peek(ToDate)), 10, 64)
+		if err != nil {
+			return nil, argError(ToDate)
+		}
+		f.toDate = &i64
+	}
+	if args.Has(FromAge) {
+		i, err := strconv.Atoi(string(args.Peek(FromAge)))
+		if err != nil {
+			return nil, argError(FromAge)
+		}
+		// the store keeps the people born before fromAge
+		date := clock.AgeBoundary(now, i, loc)
+		f.fromAge = &date
+	}
+	if args.Has(ToAge) {
+		i, err := strconv.Atoi(string(args.Peek(ToAge)))
+		if err != nil {
+			return nil, argError(ToAge)
+		}
+		// the store keeps the people born after toAge, the ones born at the boundary are younger than toAge
+		date := clock.AgeBoundary(now, i, loc) - 1
+		f.toAge = &date
+	}
+	if args.Has(Gender) {
+		str := string(args.Peek(Gender))
+		if !model.IsGender(str) {
+			return nil, argError(Gender)
+		}
+		f.gender = &str
+	}
+	return &f, nil
+}
+
+// parsePage reads limit, offset, after_visited_at, after_id and order arguments
+// returns nil page if there are none of them
+func parsePage(args *fasthttp.Args) (*store.Page, error) {
+	if !args.Has(Limit) && !args.Has(Offset) && !args.Has(AfterVisitedAt) && !args.Has(AfterID) && !args.Has(Order) {
+		return nil, nil
+	}
+	var page store.Page
+	if args.Has(Limit) {
+		limit, err := strconv.Atoi(string(args.Peek(Limit)))
+		if err != nil || limit < 0 {
+			return nil, argError(Limit)
+		}
+		page.Limit = limit
+	}
+	if args.Has(Offset) {
+		offset, err := strconv.Atoi(string(args.Peek(Offset)))
+		if err != nil || offset < 0 {
+			return nil, argError(Offset)
+		}
+		page.Offset = offset
+	}
+	// cursor should be complete
+	if !args.Has(AfterVisitedAt) && args.Has(AfterID) {
+		return nil, argError(AfterVisitedAt)
+	}
+	if args.Has(AfterVisitedAt) && !args.Has(AfterID) {
+		return nil, argError(AfterID)
+	}
+	if args.Has(AfterVisitedAt) {
+		visitedAt, err := strconv.ParseInt(string(args.Peek(AfterVisitedAt)), 10, 64)
+		if err != nil {
+			return nil, argError(AfterVisitedAt)
+		}
+		id, err := strconv.ParseInt(string(args.Peek(AfterID)), 10, 32)
+		if err != nil {
+			return nil, argError(AfterID)
+		}
+		page.After = &model.Cursor{VisitedAt: visitedAt, ID: int32(id)}
+	}
+	if args.Has(Order) {
+		switch string(args.Peek(Order)) {
+		case "asc":
+		case "desc":
+			page.Desc = true
+		default:
+			return nil, argError(Order)
+		}
+	}
+	return &page, nil
+}
+
+// parseID returns the id parsed by the router from the {id:int} path segment
 func parseID(ctx *fasthttp.RequestCtx) (int32, error) {
-	id64, err := strconv.ParseInt(string(ctx.UserValue(idLabel).([]byte)), 10, 32)
-	id := int32(id64)
-	if err != nil {
-		return 0, err
+	id, ok := ctx.UserValue(idLabel).(int32)
+	if !ok {
+		return 0, ErrParse
 	}
 	return id, nil
 }
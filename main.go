@@ -1,12 +1,20 @@
 package main
 
 import (
-	"bytes"
+	"flag"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
 	"runtime/debug"
+	"syscall"
 	"time"
 
+	"github.com/la0rg/highloadcup/clock"
+	"github.com/la0rg/highloadcup/config"
+	"github.com/la0rg/highloadcup/model"
+	"github.com/la0rg/highloadcup/router"
 	"github.com/la0rg/highloadcup/store"
 	"github.com/la0rg/highloadcup/util"
 	log "github.com/sirupsen/logrus"
@@ -14,134 +22,296 @@ import (
 )
 
 var dataStore = store.NewStore()
-var now time.Time
+
+// clk is the clock of the age filters, ageZone is the time zone of the birthdays
+var clk clock.Clock = clock.Wall
+var ageZone = time.UTC
+
+// cfg is the effective configuration loaded in main
+var cfg *config.Config
+
+// importReport is the report of the data archive import, nil if the data is loaded otherwise
+var importReport *model.ImportReport
 
 const idLabel = "id"
 const version = 7.0
 
 func main() {
 	//defer profile.Start(profile.MemProfile, profile.ProfilePath(".")).Stop()
+	var err error
+	cfg, err = config.Load(os.Args[0], os.Args[1:], os.Environ())
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.PrintOnly {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+	level, err := log.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.SetLevel(level)
 
-	debug.SetGCPercent(80)
+	debug.SetGCPercent(cfg.GCPercent)
 	log.Infof("Starting version: %f", version)
 
-	now = util.ImportCurrentTimestamp()
+	if err := setClock(); err != nil {
+		log.Fatal(err)
+	}
 	// import static data
+	policy, err := store.ParseCascadePolicy(cfg.Cascade)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dataStore.SetCascadePolicy(policy)
+
 	start := time.Now()
-	err := util.ImportDataFromZip(dataStore)
+	var wal *store.WAL
+	if cfg.Leader != "" {
+		err = startFollower()
+	} else {
+		err = loadData()
+		if err == nil {
+			wal, err = openWAL()
+		}
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Infof("Time to load data.zip: %v", time.Since(start))
+	log.Infof("Time to load data: %v", time.Since(start))
+	if cfg.Export != "" {
+		if err := exportData(cfg.Export); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+	// loaded data is not streamed to the subscribers
+	if cfg.EventsBuffer > 0 {
+		dataStore.EnableEvents(cfg.EventsBuffer)
+	}
 	runtime.GC()
+	saveSnapshotOnSignal(wal)
+	if following != nil {
+		// the leader events are applied once the store is set up
+		go following.run()
+	}
+	if err := startWatcher(wal); err != nil {
+		log.Fatal(err)
+	}
 
 	// start http server
-	log.Fatal(fasthttp.ListenAndServe(":80", manualRouting()))
+	server := &fasthttp.Server{
+		Handler:              serve(newRouter()),
+		Concurrency:          cfg.Limits.Concurrency,
+		MaxConnsPerIP:        cfg.Limits.MaxConnsPerIP,
+		MaxRequestBodySize:   cfg.Limits.MaxRequestBodySize,
+		ReadTimeout:          cfg.Limits.ReadTimeout,
+		WriteTimeout:         cfg.Limits.WriteTimeout,
+		MaxKeepaliveDuration: cfg.Limits.MaxKeepaliveDuration,
+	}
+	log.Fatal(server.ListenAndServe(cfg.Listen))
 }
 
-func manualRouting() fasthttp.RequestHandler {
-	delim := []byte{'/'}
-	users := []byte("users")
-	locations := []byte("locations")
-	visits := []byte("visits")
-	new := []byte("new")
-	avg := []byte("avg")
+// setClock creates the clock of the configured mode, the missing options file
+// of the options mode is not fatal, the wall clock is used then
+func setClock() error {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return err
+	}
+	ageZone = loc
+	value := cfg.OptionsFile
+	if cfg.Clock == clock.ModeFixed {
+		value = cfg.ClockTime
+	}
+	c, err := clock.New(cfg.Clock, value)
+	if err != nil && cfg.Clock == clock.ModeOptions {
+		log.Warnf("Could not read the time of the data from %s (%v), using the wall clock", cfg.OptionsFile, err)
+		c, err = clock.Wall, nil
+	}
+	if err != nil {
+		return err
+	}
+	clk = c
+	log.Infof("Clock: %s, now is %v, ages in %v", cfg.Clock, clk.Now(), ageZone)
+	return nil
+}
 
-	return func(ctx *fasthttp.RequestCtx) {
-		path := ctx.Path()
-		if path[0] != delim[0] {
-			NotFound(ctx)
-			return
+// loadData restores the store from the snapshot if it is newer than the data archive
+// and imports the data archive otherwise. The WAL is truncated at the snapshot,
+// so the snapshot that could not be loaded is fatal when the WAL is used
+// and the snapshot is loaded even if the data archive is newer.
+func loadData() error {
+	fresh := snapshotIsFresh(cfg.Snapshot, cfg.Data)
+	if !fresh && cfg.WAL != "" && fileExists(cfg.Snapshot) {
+		log.Warnf("Data %s is newer than snapshot %s, ignored since WAL %s continues the snapshot",
+			cfg.Data, cfg.Snapshot, cfg.WAL)
+		fresh = true
+	}
+	if fresh {
+		err := dataStore.LoadSnapshot(cfg.Snapshot)
+		if err == nil {
+			log.Infof("Loaded snapshot %s", cfg.Snapshot)
+			return nil
 		}
-		path = path[1:]
+		if cfg.WAL != "" {
+			return fmt.Errorf("%s: %v (WAL %s continues it)", cfg.Snapshot, err, cfg.WAL)
+		}
+		log.Warnf("Could not load snapshot %s: %v", cfg.Snapshot, err)
+		// drop partially loaded data
+		policy := dataStore.CascadePolicy()
+		dataStore = store.NewStore()
+		dataStore.SetCascadePolicy(policy)
+	}
+	report, err := util.ImportData(cfg.Data, util.ImportOptions{
+		Source:  cfg.DataSource,
+		Format:  cfg.DataFormat,
+		Workers: cfg.ImportWorkers,
+	}, dataStore)
+	if err != nil {
+		return err
+	}
+	util.LogImportReport(report)
+	importReport = report
+	if cfg.Strict && report.Rejected > 0 {
+		return ErrRejectedRecords
+	}
+	return nil
+}
+
+// startFollower loads the snapshot of the leader instead of the local files,
+// the events of the leader are applied after it by following.run
+func startFollower() error {
+	// all the state of the follower comes from the leader
+	cfg.Snapshot = ""
+	cfg.WAL = ""
+	// visits of the deleted entities are deleted by the events of the leader
+	dataStore.SetCascadePolicy(store.CascadeOrphan)
+	following = newReplica(cfg.Leader, dataStore)
+	return following.resync()
+}
 
+// startWatcher merges the files of the watched directory in background,
+// the follower gets the merged data from the leader.
+// A merged file is marked when its records are synced to the WAL or saved in the snapshot,
+// so the watch mode requires one of them.
+func startWatcher(wal *store.WAL) error {
+	if cfg.Watch == "" || following != nil {
+		return nil
+	}
+	var persist func() error
+	switch {
+	case wal != nil:
+		persist = wal.Sync
+	case cfg.Snapshot != "":
+		persist = saveSnapshot
+	default:
+		return ErrWatchNotDurable
+	}
+	watcher := util.NewWatcher(cfg.Watch, dataStore, util.ImportOptions{
+		Format:  cfg.DataFormat,
+		Workers: cfg.ImportWorkers,
+	}, persist)
+	log.Infof("Watching %s for the data to merge", cfg.Watch)
+	go watcher.Run(cfg.WatchInterval)
+	return nil
+}
+
+// openWAL replays the write-ahead log on top of the loaded data
+// and attaches it to the store for the new mutations
+func openWAL() (*store.WAL, error) {
+	if cfg.WAL == "" {
+		return nil, nil
+	}
+	policy, err := store.ParseSyncPolicy(cfg.WALSync)
+	if err != nil {
+		return nil, err
+	}
+	n, err := dataStore.ReplayWAL(cfg.WAL)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Replayed %d WAL records from %s", n, cfg.WAL)
+	wal, err := store.OpenWAL(cfg.WAL, policy, cfg.WALInterval)
+	if err != nil {
+		return nil, err
+	}
+	dataStore.SetWAL(wal)
+	return wal, nil
+}
+
+func snapshotIsFresh(snapshot, data string) bool {
+	if snapshot == "" {
+		return false
+	}
+	s, err := os.Stat(snapshot)
+	if err != nil {
+		return false
+	}
+	d, err := os.Stat(data)
+	if err != nil {
+		return true
+	}
+	return !s.ModTime().Before(d.ModTime())
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func saveSnapshot() error {
+	if cfg.Snapshot == "" {
+		return nil
+	}
+	start := time.Now()
+	err := dataStore.SaveSnapshot(cfg.Snapshot)
+	if err != nil {
+		return err
+	}
+	log.Infof("Saved snapshot %s in %v", cfg.Snapshot, time.Since(start))
+	return nil
+}
+
+// saveSnapshotOnSignal stores the data before the process is terminated
+func saveSnapshotOnSignal(wal *store.WAL) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		sig := <-signals
+		log.Infof("Received %v, shutting down", sig)
+		err := saveSnapshot()
+		if err == nil && wal != nil {
+			err = wal.Close()
+		}
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}()
+}
+
+// serve sets the connection headers shared by all the routes and dispatches the request
+func serve(r *router.Router) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
 		ConnKeepAlive(ctx)
 		ctx.SetStatusCode(http.StatusOK)
-		if ctx.IsGet() {
-			//parts := bytes.Split(path, delim)
-			l := bytes.Count(path, delim)
-			var part1, part2, part3 []byte
-			if l == 1 {
-				i1 := bytes.IndexByte(path, delim[0])
-				part1 = path[:i1]
-				part2 = path[i1+1:]
-			} else if l == 2 {
-				i1 := bytes.IndexByte(path, delim[0])
-				i2 := i1 + 1 + bytes.IndexByte(path[i1+1:], delim[0])
-				part1 = path[:i1]
-				part2 = path[i1+1 : i2]
-				part3 = path[i2+1:]
-			} else {
-				NotFound(ctx)
-				return
-			}
-
-			if bytes.Equal(part1, users) {
-				ctx.SetUserValue(idLabel, part2)
-				if l == 2 && bytes.Equal(part3, visits) {
-					VisitsByUser(ctx)
-				} else {
-					User(ctx)
-				}
-			} else if bytes.Equal(part1, locations) {
-				ctx.SetUserValue(idLabel, part2)
-				if l == 2 && bytes.Equal(part3, avg) {
-					LocationAvg(ctx)
-				} else {
-					Location(ctx)
-				}
-			} else if bytes.Equal(part1, visits) {
-				if l == 2 {
-					NotFound(ctx)
-					return
-				}
-				ctx.SetUserValue(idLabel, part2)
-				Visit(ctx)
-			} else {
-				NotFound(ctx)
-			}
-			return
-		}
-		if ctx.IsPost() {
+		if !ctx.IsGet() {
 			ConnClose(ctx)
-			l := bytes.Count(path, delim)
-			var part1, part2 []byte
-			if l == 1 {
-				i1 := bytes.IndexByte(path, delim[0])
-				part1 = path[:i1]
-				part2 = path[i1+1:]
-			} else {
-				NotFound(ctx)
-				return
-			}
-
-			if bytes.Equal(part1, users) {
-				if bytes.Equal(part2, new) {
-					UserCreate(ctx)
-				} else {
-					ctx.SetUserValue(idLabel, part2)
-					UserUpdate(ctx)
-				}
-			} else if bytes.Equal(part1, locations) {
-				if bytes.Equal(part2, new) {
-					LocationCreate(ctx)
-				} else {
-					ctx.SetUserValue(idLabel, part2)
-					LocationUpdate(ctx)
-				}
-			} else if bytes.Equal(part1, visits) {
-				if bytes.Equal(part2, new) {
-					VisitCreate(ctx)
-				} else {
-					ctx.SetUserValue(idLabel, part2)
-					VisitUpdate(ctx)
-				}
-			} else {
-				NotFound(ctx)
-			}
+		}
+		if readOnly(ctx) {
 			return
 		}
-
-		NotFound(ctx)
+		r.Handler(ctx)
 	}
 }
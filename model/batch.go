@@ -0,0 +1,26 @@
+package model
+
+import (
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/opt"
+)
+
+// BatchOp is a create or an update of an entity in POST /batch
+type BatchOp struct {
+	Op     string              `json:"op"`     // create or update
+	Entity string              `json:"entity"` // users, locations or visits
+	ID     opt.Int32           `json:"id"`     // id of the updated entity
+	Data   easyjson.RawMessage `json:"data"`
+}
+
+// BatchResult is the result of a single batch operation
+type BatchResult struct {
+	Status int    `json:"status"`
+	Error  *Error `json:"error,omitempty"`
+}
+
+// BatchResponse lists the results in the order of the operations
+type BatchResponse struct {
+	Applied int           `json:"applied"`
+	Results []BatchResult `json:"results"`
+}
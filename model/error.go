@@ -0,0 +1,21 @@
+package model
+
+// Error is a machine-readable description of a failed request
+type Error struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Field   string        `json:"field,omitempty"`
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// ErrorDetail describes an invalid field
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field"`
+}
+
+// ErrorResponse is the body of the failed requests
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "github.com/mailru/easyjson"
+
+// Event is a change of an entity streamed by GET /events
+type Event struct {
+	Seq     uint64              `json:"seq"`
+	Time    int64               `json:"time"`   // commit time in unix milliseconds
+	Type    string              `json:"type"`   // created, updated or deleted
+	Entity  string              `json:"entity"` // users, locations or visits
+	ID      int32               `json:"id"`
+	Changed []string            `json:"changed,omitempty"` // updated fields
+	Before  easyjson.RawMessage `json:"before,omitempty"`
+	After   easyjson.RawMessage `json:"after,omitempty"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "strconv"
+
+// FieldError is an error caused by the value of a single field
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+// FieldErrors are the errors of all the invalid fields of an entity
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return e[0].Error() + " (and " + strconv.Itoa(len(e)-1) + " more)"
+}
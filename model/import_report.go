@@ -0,0 +1,24 @@
+package model
+
+// ImportReport is the body of GET /admin/import-report
+type ImportReport struct {
+	Source   string             `json:"source"`
+	Accepted int64              `json:"accepted"`
+	Updated  int64              `json:"updated,omitempty"`
+	Rejected int64              `json:"rejected"`
+	Files    []ImportFileReport `json:"files"`
+	// Skipped are the files of the unknown entities or formats
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// ImportFileReport counts the records of an imported file
+type ImportFileReport struct {
+	Name     string `json:"name"`
+	Entity   string `json:"entity"`
+	Accepted int64  `json:"accepted"`
+	// Updated are the accepted records of the existing ids in the merge mode
+	Updated  int64 `json:"updated,omitempty"`
+	Rejected int64 `json:"rejected"`
+	// Reasons counts the rejected records by the reason
+	Reasons map[string]int64 `json:"reasons"`
+}
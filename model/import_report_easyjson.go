@@ -0,0 +1,336 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package model
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonB9edf9feDecodeGithubComLa0rgHighloadcupModel(in *jlexer.Lexer, out *ImportReport) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "source":
+			out.Source = string(in.String())
+		case "accepted":
+			out.Accepted = int64(in.Int64())
+		case "updated":
+			out.Updated = int64(in.Int64())
+		case "rejected":
+			out.Rejected = int64(in.Int64())
+		case "files":
+			if in.IsNull() {
+				in.Skip()
+				out.Files = nil
+			} else {
+				in.Delim('[')
+				if out.Files == nil {
+					if !in.IsDelim(']') {
+						out.Files = make([]ImportFileReport, 0, 1)
+					} else {
+						out.Files = []ImportFileReport{}
+					}
+				} else {
+					out.Files = (out.Files)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 ImportFileReport
+					(v1).UnmarshalEasyJSON(in)
+					out.Files = append(out.Files, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "skipped":
+			if in.IsNull() {
+				in.Skip()
+				out.Skipped = nil
+			} else {
+				in.Delim('[')
+				if out.Skipped == nil {
+					if !in.IsDelim(']') {
+						out.Skipped = make([]string, 0, 4)
+					} else {
+						out.Skipped = []string{}
+					}
+				} else {
+					out.Skipped = (out.Skipped)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v2 string
+					v2 = string(in.String())
+					out.Skipped = append(out.Skipped, v2)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonB9edf9feEncodeGithubComLa0rgHighloadcupModel(out *jwriter.Writer, in ImportReport) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"source\":")
+	out.String(string(in.Source))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"accepted\":")
+	out.Int64(int64(in.Accepted))
+	if in.Updated != 0 {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"updated\":")
+		out.Int64(int64(in.Updated))
+	}
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"rejected\":")
+	out.Int64(int64(in.Rejected))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"files\":")
+	if in.Files == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v3, v4 := range in.Files {
+			if v3 > 0 {
+				out.RawByte(',')
+			}
+			(v4).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+	if len(in.Skipped) != 0 {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"skipped\":")
+		if in.Skipped == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v5, v6 := range in.Skipped {
+				if v5 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v6))
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ImportReport) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonB9edf9feEncodeGithubComLa0rgHighloadcupModel(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ImportReport) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonB9edf9feEncodeGithubComLa0rgHighloadcupModel(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ImportReport) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonB9edf9feDecodeGithubComLa0rgHighloadcupModel(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ImportReport) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonB9edf9feDecodeGithubComLa0rgHighloadcupModel(l, v)
+}
+func easyjsonB9edf9feDecodeGithubComLa0rgHighloadcupModel1(in *jlexer.Lexer, out *ImportFileReport) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "name":
+			out.Name = string(in.String())
+		case "entity":
+			out.Entity = string(in.String())
+		case "accepted":
+			out.Accepted = int64(in.Int64())
+		case "updated":
+			out.Updated = int64(in.Int64())
+		case "rejected":
+			out.Rejected = int64(in.Int64())
+		case "reasons":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Reasons = make(map[string]int64)
+				} else {
+					out.Reasons = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v7 int64
+					v7 = int64(in.Int64())
+					(out.Reasons)[key] = v7
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonB9edf9feEncodeGithubComLa0rgHighloadcupModel1(out *jwriter.Writer, in ImportFileReport) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"name\":")
+	out.String(string(in.Name))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"entity\":")
+	out.String(string(in.Entity))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"accepted\":")
+	out.Int64(int64(in.Accepted))
+	if in.Updated != 0 {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"updated\":")
+		out.Int64(int64(in.Updated))
+	}
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"rejected\":")
+	out.Int64(int64(in.Rejected))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"reasons\":")
+	if in.Reasons == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {
+		out.RawString(`null`)
+	} else {
+		out.RawByte('{')
+		v8First := true
+		for v8Name, v8Value := range in.Reasons {
+			if !v8First {
+				out.RawByte(',')
+			}
+			v8First = false
+			out.String(string(v8Name))
+			out.RawByte(':')
+			out.Int64(int64(v8Value))
+		}
+		out.RawByte('}')
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ImportFileReport) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonB9edf9feEncodeGithubComLa0rgHighloadcupModel1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ImportFileReport) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonB9edf9feEncodeGithubComLa0rgHighloadcupModel1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ImportFileReport) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonB9edf9feDecodeGithubComLa0rgHighloadcupModel1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ImportFileReport) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonB9edf9feDecodeGithubComLa0rgHighloadcupModel1(l, v)
+}
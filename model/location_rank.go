@@ -0,0 +1,16 @@
+package model
+
+// LocationRank is a location with the aggregated marks of its visits
+type LocationRank struct {
+	ID      int32   `json:"id"`
+	Place   string  `json:"place"`
+	Country string  `json:"country"`
+	City    string  `json:"city"`
+	Avg     float64 `json:"avg"`
+	Visits  int     `json:"visits"`
+}
+
+// LocationRankArray is a list of the best locations
+type LocationRankArray struct {
+	Locations []LocationRank `json:"locations"`
+}
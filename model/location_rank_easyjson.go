@@ -0,0 +1,225 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package model
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonDb8e5a30DecodeGithubComLa0rgHighloadcupModel(in *jlexer.Lexer, out *LocationRankArray) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "locations":
+			if in.IsNull() {
+				in.Skip()
+				out.Locations = nil
+			} else {
+				in.Delim('[')
+				if out.Locations == nil {
+					if !in.IsDelim(']') {
+						out.Locations = make([]LocationRank, 0, 1)
+					} else {
+						out.Locations = []LocationRank{}
+					}
+				} else {
+					out.Locations = (out.Locations)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 LocationRank
+					(v1).UnmarshalEasyJSON(in)
+					out.Locations = append(out.Locations, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonDb8e5a30EncodeGithubComLa0rgHighloadcupModel(out *jwriter.Writer, in LocationRankArray) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"locations\":")
+	if in.Locations == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v2, v3 := range in.Locations {
+			if v2 > 0 {
+				out.RawByte(',')
+			}
+			(v3).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v LocationRankArray) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonDb8e5a30EncodeGithubComLa0rgHighloadcupModel(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v LocationRankArray) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonDb8e5a30EncodeGithubComLa0rgHighloadcupModel(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *LocationRankArray) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonDb8e5a30DecodeGithubComLa0rgHighloadcupModel(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *LocationRankArray) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonDb8e5a30DecodeGithubComLa0rgHighloadcupModel(l, v)
+}
+func easyjsonDb8e5a30DecodeGithubComLa0rgHighloadcupModel1(in *jlexer.Lexer, out *LocationRank) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.ID = int32(in.Int32())
+		case "place":
+			out.Place = string(in.String())
+		case "country":
+			out.Country = string(in.String())
+		case "city":
+			out.City = string(in.String())
+		case "avg":
+			out.Avg = float64(in.Float64())
+		case "visits":
+			out.Visits = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonDb8e5a30EncodeGithubComLa0rgHighloadcupModel1(out *jwriter.Writer, in LocationRank) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"id\":")
+	out.Int32(int32(in.ID))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"place\":")
+	out.String(string(in.Place))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"country\":")
+	out.String(string(in.Country))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"city\":")
+	out.String(string(in.City))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"avg\":")
+	out.Float64(float64(in.Avg))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"visits\":")
+	out.Int(int(in.Visits))
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v LocationRank) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonDb8e5a30EncodeGithubComLa0rgHighloadcupModel1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v LocationRank) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonDb8e5a30EncodeGithubComLa0rgHighloadcupModel1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *LocationRank) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonDb8e5a30DecodeGithubComLa0rgHighloadcupModel1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *LocationRank) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonDb8e5a30DecodeGithubComLa0rgHighloadcupModel1(l, v)
+}
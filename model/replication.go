@@ -0,0 +1,16 @@
+package model
+
+import "github.com/mailru/easyjson/opt"
+
+// ReplicationStatus is the body of GET /replication/status
+type ReplicationStatus struct {
+	Role   string `json:"role"`             // leader or follower
+	Leader string `json:"leader,omitempty"` // address of the leader of the follower
+	// Seq is the last event of the node for the leader and the last applied leader event for the follower
+	Seq uint64 `json:"seq"`
+	// the fields below are set for the follower
+	Connected opt.Bool   `json:"connected,omitempty"`
+	LeaderSeq opt.Uint64 `json:"leader_seq,omitempty"` // undefined if the leader is not reachable
+	LagEvents opt.Uint64 `json:"lag_events,omitempty"` // leader events that are not applied yet
+	LagMillis opt.Int64  `json:"lag_ms,omitempty"`     // time since the commit of the last applied event if it is behind
+}
@@ -0,0 +1,144 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package model
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson1381f0f8DecodeGithubComLa0rgHighloadcupModel(in *jlexer.Lexer, out *ReplicationStatus) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "role":
+			out.Role = string(in.String())
+		case "leader":
+			out.Leader = string(in.String())
+		case "seq":
+			out.Seq = uint64(in.Uint64())
+		case "connected":
+			(out.Connected).UnmarshalEasyJSON(in)
+		case "leader_seq":
+			(out.LeaderSeq).UnmarshalEasyJSON(in)
+		case "lag_events":
+			(out.LagEvents).UnmarshalEasyJSON(in)
+		case "lag_ms":
+			(out.LagMillis).UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson1381f0f8EncodeGithubComLa0rgHighloadcupModel(out *jwriter.Writer, in ReplicationStatus) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"role\":")
+	out.String(string(in.Role))
+	if in.Leader != "" {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"leader\":")
+		out.String(string(in.Leader))
+	}
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"seq\":")
+	out.Uint64(uint64(in.Seq))
+	if (in.Connected).IsDefined() {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"connected\":")
+		(in.Connected).MarshalEasyJSON(out)
+	}
+	if (in.LeaderSeq).IsDefined() {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"leader_seq\":")
+		(in.LeaderSeq).MarshalEasyJSON(out)
+	}
+	if (in.LagEvents).IsDefined() {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"lag_events\":")
+		(in.LagEvents).MarshalEasyJSON(out)
+	}
+	if (in.LagMillis).IsDefined() {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"lag_ms\":")
+		(in.LagMillis).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ReplicationStatus) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson1381f0f8EncodeGithubComLa0rgHighloadcupModel(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ReplicationStatus) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson1381f0f8EncodeGithubComLa0rgHighloadcupModel(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ReplicationStatus) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson1381f0f8DecodeGithubComLa0rgHighloadcupModel(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ReplicationStatus) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson1381f0f8DecodeGithubComLa0rgHighloadcupModel(l, v)
+}
@@ -0,0 +1,11 @@
+package model
+
+// Stats describes the marks of the location visits
+type Stats struct {
+	Count     int     `json:"count"`
+	Visitors  int     `json:"visitors"`
+	Histogram [6]int  `json:"histogram"`
+	Avg       float64 `json:"avg"`
+	Median    float64 `json:"median"`
+	StdDev    float64 `json:"stddev"`
+}
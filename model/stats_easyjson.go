@@ -0,0 +1,148 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package model
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonE3ab7953DecodeGithubComLa0rgHighloadcupModel(in *jlexer.Lexer, out *Stats) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "count":
+			out.Count = int(in.Int())
+		case "visitors":
+			out.Visitors = int(in.Int())
+		case "histogram":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('[')
+				v1 := 0
+				for !in.IsDelim(']') {
+					if v1 < 6 {
+						out.Histogram[v1] = int(in.Int())
+						v1++
+					} else {
+						in.SkipRecursive()
+					}
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "avg":
+			out.Avg = float64(in.Float64())
+		case "median":
+			out.Median = float64(in.Float64())
+		case "stddev":
+			out.StdDev = float64(in.Float64())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonE3ab7953EncodeGithubComLa0rgHighloadcupModel(out *jwriter.Writer, in Stats) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"count\":")
+	out.Int(int(in.Count))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"visitors\":")
+	out.Int(int(in.Visitors))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"histogram\":")
+	out.RawByte('[')
+	for v2 := range in.Histogram {
+		if v2 > 0 {
+			out.RawByte(',')
+		}
+		out.Int(int(in.Histogram[v2]))
+	}
+	out.RawByte(']')
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"avg\":")
+	out.Float64(float64(in.Avg))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"median\":")
+	out.Float64(float64(in.Median))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"stddev\":")
+	out.Float64(float64(in.StdDev))
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Stats) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonE3ab7953EncodeGithubComLa0rgHighloadcupModel(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Stats) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonE3ab7953EncodeGithubComLa0rgHighloadcupModel(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Stats) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonE3ab7953DecodeGithubComLa0rgHighloadcupModel(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Stats) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonE3ab7953DecodeGithubComLa0rgHighloadcupModel(l, v)
+}
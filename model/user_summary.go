@@ -0,0 +1,12 @@
+package model
+
+// UserSummary describes the travels of a user
+type UserSummary struct {
+	Visits         int     `json:"visits"`
+	Countries      int     `json:"countries"`
+	Locations      int     `json:"locations"`
+	Avg            float64 `json:"avg"`
+	FirstVisitedAt *int64  `json:"first_visited_at,omitempty"`
+	LastVisitedAt  *int64  `json:"last_visited_at,omitempty"`
+	MaxDistance    int32   `json:"max_distance"`
+}
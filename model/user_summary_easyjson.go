@@ -0,0 +1,162 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package model
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson3e8272d0DecodeGithubComLa0rgHighloadcupModel(in *jlexer.Lexer, out *UserSummary) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "visits":
+			out.Visits = int(in.Int())
+		case "countries":
+			out.Countries = int(in.Int())
+		case "locations":
+			out.Locations = int(in.Int())
+		case "avg":
+			out.Avg = float64(in.Float64())
+		case "first_visited_at":
+			if in.IsNull() {
+				in.Skip()
+				out.FirstVisitedAt = nil
+			} else {
+				if out.FirstVisitedAt == nil {
+					out.FirstVisitedAt = new(int64)
+				}
+				*out.FirstVisitedAt = int64(in.Int64())
+			}
+		case "last_visited_at":
+			if in.IsNull() {
+				in.Skip()
+				out.LastVisitedAt = nil
+			} else {
+				if out.LastVisitedAt == nil {
+					out.LastVisitedAt = new(int64)
+				}
+				*out.LastVisitedAt = int64(in.Int64())
+			}
+		case "max_distance":
+			out.MaxDistance = int32(in.Int32())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson3e8272d0EncodeGithubComLa0rgHighloadcupModel(out *jwriter.Writer, in UserSummary) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"visits\":")
+	out.Int(int(in.Visits))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"countries\":")
+	out.Int(int(in.Countries))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"locations\":")
+	out.Int(int(in.Locations))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"avg\":")
+	out.Float64(float64(in.Avg))
+	if in.FirstVisitedAt != nil {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"first_visited_at\":")
+		if in.FirstVisitedAt == nil {
+			out.RawString("null")
+		} else {
+			out.Int64(int64(*in.FirstVisitedAt))
+		}
+	}
+	if in.LastVisitedAt != nil {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"last_visited_at\":")
+		if in.LastVisitedAt == nil {
+			out.RawString("null")
+		} else {
+			out.Int64(int64(*in.LastVisitedAt))
+		}
+	}
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"max_distance\":")
+	out.Int32(int32(in.MaxDistance))
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v UserSummary) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson3e8272d0EncodeGithubComLa0rgHighloadcupModel(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v UserSummary) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson3e8272d0EncodeGithubComLa0rgHighloadcupModel(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *UserSummary) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson3e8272d0DecodeGithubComLa0rgHighloadcupModel(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *UserSummary) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson3e8272d0DecodeGithubComLa0rgHighloadcupModel(l, v)
+}
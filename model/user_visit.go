@@ -14,6 +14,13 @@ func UserVisitFromVisit(v Visit) UserVisit {
 	}
 }
 
+// Cursor points to the visit the next page starts after
+type Cursor struct {
+	VisitedAt int64 `json:"visited_at"`
+	ID        int32 `json:"id"`
+}
+
 type UserVisitArray struct {
 	Visits []UserVisit `json:"visits"`
+	Next   *Cursor     `json:"next,omitempty"`
 }
@@ -59,6 +59,16 @@ func easyjson15d499e9DecodeGithubComLa0rgHighloadcupModel(in *jlexer.Lexer, out
 				}
 				in.Delim(']')
 			}
+		case "next":
+			if in.IsNull() {
+				in.Skip()
+				out.Next = nil
+			} else {
+				if out.Next == nil {
+					out.Next = new(Cursor)
+				}
+				(*out.Next).UnmarshalEasyJSON(in)
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -90,6 +100,18 @@ func easyjson15d499e9EncodeGithubComLa0rgHighloadcupModel(out *jwriter.Writer, i
 		}
 		out.RawByte(']')
 	}
+	if in.Next != nil {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"next\":")
+		if in.Next == nil {
+			out.RawString("null")
+		} else {
+			(*in.Next).MarshalEasyJSON(out)
+		}
+	}
 	out.RawByte('}')
 }
 
@@ -199,3 +221,78 @@ func (v *UserVisit) UnmarshalJSON(data []byte) error {
 func (v *UserVisit) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjson15d499e9DecodeGithubComLa0rgHighloadcupModel1(l, v)
 }
+func easyjson15d499e9DecodeGithubComLa0rgHighloadcupModel2(in *jlexer.Lexer, out *Cursor) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "visited_at":
+			out.VisitedAt = int64(in.Int64())
+		case "id":
+			out.ID = int32(in.Int32())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson15d499e9EncodeGithubComLa0rgHighloadcupModel2(out *jwriter.Writer, in Cursor) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"visited_at\":")
+	out.Int64(int64(in.VisitedAt))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"id\":")
+	out.Int32(int32(in.ID))
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Cursor) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson15d499e9EncodeGithubComLa0rgHighloadcupModel2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Cursor) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson15d499e9EncodeGithubComLa0rgHighloadcupModel2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Cursor) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson15d499e9DecodeGithubComLa0rgHighloadcupModel2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Cursor) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson15d499e9DecodeGithubComLa0rgHighloadcupModel2(l, v)
+}
@@ -0,0 +1,192 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/opt"
+)
+
+var (
+	ErrInvalidEmail     = errors.New("Invalid email")
+	ErrInvalidGender    = errors.New("Gender should be m or f")
+	ErrInvalidMark      = errors.New("Mark should be from 0 to 5")
+	ErrInvalidBirthDate = errors.New("Birth date is out of range")
+	ErrTooLong          = errors.New("Value is too long")
+)
+
+// Limits of the field values (string lengths are in characters)
+const (
+	MaxEmailLen   = 100
+	MaxNameLen    = 50
+	MaxCountryLen = 50
+	MaxCityLen    = 50
+	MaxPlaceLen   = 1000
+	MaxMark       = 5
+	// 01.01.1930 and 01.01.1999 UTC
+	MinBirthDate = -1262304000
+	MaxBirthDate = 915148800
+)
+
+// validator collects the errors of the invalid fields
+type validator FieldErrors
+
+func (v *validator) check(valid bool, field string, err error) {
+	if !valid {
+		*v = append(*v, &FieldError{Field: field, Err: err})
+	}
+}
+
+func (v *validator) maxLen(s opt.String, max int, field string) {
+	v.check(!s.Defined || utf8.RuneCountInString(s.V) <= max, field, ErrTooLong)
+}
+
+func (v validator) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return FieldErrors(v)
+}
+
+// ValidateUser checks the defined fields of the user,
+// so it is used both for the new users and for the partial updates
+func ValidateUser(u *User) error {
+	var v validator
+	v.maxLen(u.Email, MaxEmailLen, "email")
+	v.check(!u.Email.Defined || IsEmail(u.Email.V), "email", ErrInvalidEmail)
+	v.maxLen(u.FirstName, MaxNameLen, "first_name")
+	v.maxLen(u.LastName, MaxNameLen, "last_name")
+	v.check(!u.Gender.Defined || IsGender(u.Gender.V), "gender", ErrInvalidGender)
+	v.check(!u.BirthDate.Defined || MinBirthDate <= u.BirthDate.V && u.BirthDate.V <= MaxBirthDate,
+		"birth_date", ErrInvalidBirthDate)
+	return v.err()
+}
+
+// ValidateLocation checks the defined fields of the location
+func ValidateLocation(l *Location) error {
+	var v validator
+	v.maxLen(l.Place, MaxPlaceLen, "place")
+	v.maxLen(l.Country, MaxCountryLen, "country")
+	v.maxLen(l.City, MaxCityLen, "city")
+	return v.err()
+}
+
+// ValidateVisit checks the defined fields of the visit
+func ValidateVisit(visit *Visit) error {
+	var v validator
+	v.check(!visit.Mark.Defined || visit.Mark.V <= MaxMark, "mark", ErrInvalidMark)
+	return v.err()
+}
+
+// IsGender checks that s is one of the genders: m or f
+func IsGender(s string) bool {
+	return s == "m" || s == "f"
+}
+
+// IsEmail checks that s looks like local@domain.tld
+func IsEmail(s string) bool {
+	at := strings.IndexByte(s, '@')
+	if at <= 0 || at != strings.LastIndexByte(s, '@') {
+		return false
+	}
+	domain := s[at+1:]
+	dot := strings.LastIndexByte(domain, '.')
+	if dot <= 0 || dot == len(domain)-1 {
+		return false
+	}
+	return strings.IndexFunc(s, unicode.IsSpace) == -1
+}
+
+// unmarshalStrict decodes the JSON object from data in a single pass like the generated decoders do,
+// field returns the decoder of the field by its key (nil for the unknown keys).
+// Unlike the generated decoders, it returns ErrNullField for every field with null value:
+// easyjson decodes null as an undefined optional field, so it can't be told from a missing one.
+// Syntax errors are returned before the null fields.
+func unmarshalStrict(data []byte, field func(key string) easyjson.Unmarshaler) error {
+	var v validator
+	in := jlexer.Lexer{Data: data}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			// key refers to data
+			v.check(false, string([]byte(key)), ErrNullField)
+		} else if f := field(key); f != nil {
+			f.UnmarshalEasyJSON(&in)
+		} else {
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	in.Consumed()
+	if err := in.Error(); err != nil {
+		return err
+	}
+	return v.err()
+}
+
+// UnmarshalStrict decodes the user from data, the fields with null value are FieldErrors
+func (u *User) UnmarshalStrict(data []byte) error {
+	return unmarshalStrict(data, func(key string) easyjson.Unmarshaler {
+		switch key {
+		case "id":
+			return &u.ID
+		case "email":
+			return &u.Email
+		case "first_name":
+			return &u.FirstName
+		case "last_name":
+			return &u.LastName
+		case "gender":
+			return &u.Gender
+		case "birth_date":
+			return &u.BirthDate
+		}
+		return nil
+	})
+}
+
+// UnmarshalStrict decodes the location from data, the fields with null value are FieldErrors
+func (l *Location) UnmarshalStrict(data []byte) error {
+	return unmarshalStrict(data, func(key string) easyjson.Unmarshaler {
+		switch key {
+		case "id":
+			return &l.ID
+		case "place":
+			return &l.Place
+		case "country":
+			return &l.Country
+		case "city":
+			return &l.City
+		case "distance":
+			return &l.Distance
+		}
+		return nil
+	})
+}
+
+// UnmarshalStrict decodes the visit from data, the fields with null value are FieldErrors
+func (visit *Visit) UnmarshalStrict(data []byte) error {
+	return unmarshalStrict(data, func(key string) easyjson.Unmarshaler {
+		switch key {
+		case "id":
+			return &visit.ID
+		case "location":
+			return &visit.LocationID
+		case "user":
+			return &visit.UserID
+		case "visited_at":
+			return &visit.VisitedAt
+		case "mark":
+			return &visit.Mark
+		}
+		return nil
+	})
+}
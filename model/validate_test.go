@@ -0,0 +1,117 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mailru/easyjson/opt"
+)
+
+func TestUnmarshalStrict(t *testing.T) {
+	cases := []struct {
+		data   string
+		fields []string
+		syntax bool
+	}{
+		{`{"email": null}`, []string{"email"}, false},
+		{`{"email":null,"gender":"m","birth_date" : null}`, []string{"email", "birth_date"}, false},
+		{`{"first_name": "is it: null"}`, nil, false},
+		{`{"extra": {"nested": null}, "gender": "m"}`, nil, false},
+		{`{}`, nil, false},
+		{`{"email": null, "gender": nul`, nil, true},
+		{`{"first_name": 1}`, nil, true},
+		{`[null]`, nil, true},
+		{`{} {}`, nil, true},
+	}
+	for _, c := range cases {
+		var u User
+		err := u.UnmarshalStrict([]byte(c.data))
+		if c.syntax {
+			if _, ok := err.(FieldErrors); ok || err == nil {
+				t.Errorf("%s: expected syntax error, got %v", c.data, err)
+			}
+			continue
+		}
+		if c.fields == nil {
+			if err != nil {
+				t.Errorf("%s: expected no nulls, got %v", c.data, err)
+			}
+			continue
+		}
+		errs, ok := err.(FieldErrors)
+		if !ok || len(errs) != len(c.fields) {
+			t.Errorf("%s: expected nulls %v, got %v", c.data, c.fields, err)
+			continue
+		}
+		for i, e := range errs {
+			if e.Field != c.fields[i] || e.Err != ErrNullField {
+				t.Errorf("%s: expected null %s, got %v", c.data, c.fields[i], e)
+			}
+		}
+	}
+
+	// the fields are decoded in the same pass
+	var u User
+	if err := u.UnmarshalStrict([]byte(`{"id": 1, "email": "a@b.c", "first_name": "a", "last_name": "b",
+		"gender": "m", "birth_date": -10, "extra": [1]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID.V != 1 || u.Email.V != "a@b.c" || u.FirstName.V != "a" || u.LastName.V != "b" ||
+		u.Gender.V != "m" || u.BirthDate.V != -10 || !u.BirthDate.Defined {
+		t.Errorf("unexpected user %+v", u)
+	}
+	var l Location
+	if err := l.UnmarshalStrict([]byte(`{"id": 2, "place": "p", "country": "c", "city": "x", "distance": 5}`)); err != nil ||
+		l.ID.V != 2 || l.Place.V != "p" || l.Country.V != "c" || l.City.V != "x" || l.Distance.V != 5 {
+		t.Errorf("unexpected location %+v, %v", l, err)
+	}
+	var v Visit
+	if err := v.UnmarshalStrict([]byte(`{"id": 3, "location": 2, "user": 1, "visited_at": 7, "mark": 5}`)); err != nil ||
+		v.ID.V != 3 || v.LocationID.V != 2 || v.UserID.V != 1 || v.VisitedAt.V != 7 || v.Mark.V != 5 {
+		t.Errorf("unexpected visit %+v, %v", v, err)
+	}
+	if err := v.UnmarshalStrict([]byte(`{"mark": null}`)); err == nil {
+		t.Error("null mark of the visit is decoded")
+	}
+}
+
+func TestValidateUser(t *testing.T) {
+	cases := []struct {
+		user  User
+		field string
+		err   error
+	}{
+		{User{Email: opt.OString("a@b.c"), Gender: opt.OString("f"), BirthDate: opt.OInt64(0)}, "", nil},
+		{User{}, "", nil},
+		{User{Email: opt.OString("ab.c")}, "email", ErrInvalidEmail},
+		{User{Email: opt.OString("a@b@c.d")}, "email", ErrInvalidEmail},
+		{User{Email: opt.OString("a@bc.")}, "email", ErrInvalidEmail},
+		{User{Email: opt.OString("a b@c.d")}, "email", ErrInvalidEmail},
+		{User{Email: opt.OString(strings.Repeat("a", 96) + "@b.cd")}, "email", ErrTooLong},
+		{User{FirstName: opt.OString(strings.Repeat("я", MaxNameLen))}, "", nil},
+		{User{LastName: opt.OString(strings.Repeat("я", MaxNameLen+1))}, "last_name", ErrTooLong},
+		{User{Gender: opt.OString("x")}, "gender", ErrInvalidGender},
+		{User{BirthDate: opt.OInt64(MinBirthDate)}, "", nil},
+		{User{BirthDate: opt.OInt64(MaxBirthDate + 1)}, "birth_date", ErrInvalidBirthDate},
+	}
+	for _, c := range cases {
+		err := ValidateUser(&c.user)
+		if c.err == nil {
+			if err != nil {
+				t.Errorf("%+v: expected valid user, got %v", c.user, err)
+			}
+			continue
+		}
+		errs, ok := err.(FieldErrors)
+		if !ok || len(errs) != 1 || errs[0].Field != c.field || errs[0].Err != c.err {
+			t.Errorf("%+v: expected %s: %v, got %v", c.user, c.field, c.err, err)
+		}
+	}
+
+	if err := ValidateVisit(&Visit{Mark: opt.OUint8(6)}); err == nil {
+		t.Error("mark 6 is valid")
+	}
+	if err := ValidateLocation(&Location{City: opt.OString(strings.Repeat("c", MaxCityLen+1))}); err == nil {
+		t.Error("long city is valid")
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/la0rg/highloadcup/store"
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/opt"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	ErrReadOnly    = errors.New("Follower does not accept writes, send them to the leader")
+	ErrEventType   = errors.New("Unknown event type or entity")
+	ErrStreamEnded = errors.New("Event stream is closed by the leader")
+)
+
+const (
+	// eventSeqHeader is the sequence number of the last event included into the replication snapshot
+	eventSeqHeader = "X-Event-Seq"
+	// eventEpochHeader identifies the run of the process numbering the events,
+	// a restarted leader numbers them from 1 again
+	eventEpochHeader = "X-Event-Epoch"
+)
+
+// eventEpoch is sent with the snapshots and the events
+var eventEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+const (
+	// replicationRetry is the pause before reconnecting to the leader
+	replicationRetry = time.Second
+	// maxEventLen is the maximum length of an event line in the stream
+	maxEventLen = 1 << 20
+)
+
+// following is the replication state of the follower, it is nil for the leader
+var following *replica
+
+// ReplicationSnapshot returns the binary snapshot of the store for the followers,
+// X-Event-Seq header is the last event included into it, the followers stream /events after it
+// events are disabled - 404
+func ReplicationSnapshot(ctx *fasthttp.RequestCtx) {
+	if cfg.EventsBuffer <= 0 {
+		writeError(ctx, http.StatusNotFound, store.ErrEventsDisabled)
+		return
+	}
+	// the snapshot is encoded in memory so slow followers do not hold the store locks
+	var b bytes.Buffer
+	seq, err := dataStore.WriteSnapshotSeq(&b)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Response.Header.Set(eventSeqHeader, strconv.FormatUint(seq, 10))
+	ctx.Response.Header.Set(eventEpochHeader, eventEpoch)
+	ctx.SetContentType("application/octet-stream")
+	ctx.SetBody(b.Bytes())
+}
+
+// ReplicationStatus returns the role of the node and the replication lag of the follower
+func ReplicationStatus(ctx *fasthttp.RequestCtx) {
+	status := model.ReplicationStatus{Role: "leader", Seq: dataStore.LastEventSeq()}
+	if following != nil {
+		status = following.status()
+	}
+	err := writeStructAsJSON(ctx, status)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// replica keeps the store of the follower in sync with the leader:
+// it loads the leader snapshot and applies the leader events after it
+type replica struct {
+	leader string
+	store  *store.Store
+	// stream has no timeout, the heartbeats of the leader are watched instead
+	stream *http.Client
+	client *http.Client
+	// ctx is canceled by stop to interrupt the requests of run
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mx sync.Mutex
+	// seq is the last applied leader event committed at time
+	epoch     string
+	seq       uint64
+	time      time.Time
+	connected bool
+}
+
+func newReplica(leader string, s *store.Store) *replica {
+	if !strings.Contains(leader, "://") {
+		leader = "http://" + leader
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &replica{
+		leader: strings.TrimSuffix(leader, "/"),
+		store:  s,
+		stream: &http.Client{},
+		client: &http.Client{Timeout: time.Minute},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// run keeps applying the leader events after the snapshot loaded by resync until stop is called,
+// the store should be set up before (see Store.EnableEvents)
+func (r *replica) run() {
+	for {
+		err := r.follow()
+		r.mx.Lock()
+		r.connected = false
+		r.mx.Unlock()
+		if r.stopped() {
+			return
+		}
+		if err != store.ErrEventsLost {
+			log.Warnf("Replication from %s is interrupted (%v), reconnecting", r.leader, err)
+			if !r.pause() {
+				return
+			}
+			continue
+		}
+		log.Warnf("Events of %s after %d are lost, loading the snapshot again", r.leader, r.lastSeq())
+		for {
+			err = r.resync()
+			if err == nil {
+				break
+			}
+			if r.stopped() {
+				return
+			}
+			log.Errorf("Could not load the snapshot of %s: %v", r.leader, err)
+			if !r.pause() {
+				return
+			}
+		}
+	}
+}
+
+// stop interrupts run, the replica does not reconnect to the leader after it
+func (r *replica) stop() {
+	r.cancel()
+}
+
+func (r *replica) stopped() bool {
+	return r.ctx.Err() != nil
+}
+
+// pause waits before reconnecting to the leader, it returns false if the replica is stopped
+func (r *replica) pause() bool {
+	select {
+	case <-r.ctx.Done():
+		return false
+	case <-time.After(replicationRetry):
+		return true
+	}
+}
+
+func (r *replica) lastSeq() uint64 {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.seq
+}
+
+// resync replaces the store with the leader snapshot
+func (r *replica) resync() error {
+	resp, err := r.get(r.client, "/replication/snapshot")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("snapshot status %d", resp.StatusCode)
+	}
+	seq, err := strconv.ParseUint(resp.Header.Get(eventSeqHeader), 10, 64)
+	if err != nil {
+		return err
+	}
+	s := store.NewStore()
+	err = s.ReadSnapshot(bufio.NewReader(resp.Body))
+	if err != nil {
+		return err
+	}
+	r.store.Replace(s)
+	r.mx.Lock()
+	r.epoch = resp.Header.Get(eventEpochHeader)
+	r.seq = seq
+	r.time = time.Time{}
+	r.mx.Unlock()
+	log.Infof("Loaded the snapshot of %s at event %d", r.leader, seq)
+	return nil
+}
+
+// get requests the path of the leader, the request is canceled by stop
+func (r *replica) get(client *http.Client, path string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, r.leader+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req.WithContext(r.ctx))
+}
+
+// follow applies the leader events until the stream is broken
+func (r *replica) follow() error {
+	r.mx.Lock()
+	epoch, seq := r.epoch, r.seq
+	r.mx.Unlock()
+	resp, err := r.get(r.stream, "/events?"+Since+"="+strconv.FormatUint(seq, 10))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusGone:
+		return store.ErrEventsLost
+	default:
+		return fmt.Errorf("events status %d", resp.StatusCode)
+	}
+	if resp.Header.Get(eventEpochHeader) != epoch {
+		// the leader is restarted, its events do not continue the snapshot
+		return store.ErrEventsLost
+	}
+	r.mx.Lock()
+	r.connected = true
+	r.mx.Unlock()
+
+	// the leader sends a heartbeat to idle subscribers, the connection is dead without it
+	watchdog := time.AfterFunc(3*eventsHeartbeat, func() { resp.Body.Close() })
+	defer watchdog.Stop()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), maxEventLen)
+	var data []byte
+	for scanner.Scan() {
+		watchdog.Reset(3 * eventsHeartbeat)
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0 && data != nil:
+			r.apply(data)
+			data = nil
+		case bytes.HasPrefix(line, []byte("data: ")):
+			data = append(data[:0], line[len("data: "):]...)
+		case bytes.HasPrefix(line, []byte("event: error")):
+			// the follower is too slow, the next request gets 410 if the events are lost
+			return ErrStreamEnded
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return ErrStreamEnded
+}
+
+func (r *replica) apply(data []byte) {
+	var e model.Event
+	err := easyjson.Unmarshal(data, &e)
+	if err == nil {
+		err = applyEvent(r.store, &e)
+	}
+	if err != nil {
+		// the event is skipped to keep the order of the rest
+		log.Errorf("Could not apply event %d of %s: %v", e.Seq, r.leader, err)
+	}
+	r.mx.Lock()
+	r.seq = e.Seq
+	r.time = time.Unix(0, e.Time*int64(time.Millisecond))
+	r.mx.Unlock()
+}
+
+// status reports the applied events and asks the leader for its last event to compute the lag
+func (r *replica) status() model.ReplicationStatus {
+	r.mx.Lock()
+	status := model.ReplicationStatus{Role: "follower", Leader: r.leader, Seq: r.seq, Connected: opt.OBool(r.connected)}
+	applied := r.time
+	r.mx.Unlock()
+
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Get(r.leader + "/replication/status")
+	if err != nil {
+		return status
+	}
+	defer resp.Body.Close()
+	var leader model.ReplicationStatus
+	if resp.StatusCode != http.StatusOK || easyjson.UnmarshalFromReader(resp.Body, &leader) != nil {
+		return status
+	}
+	status.LeaderSeq = opt.OUint64(leader.Seq)
+	var lag uint64
+	var lagTime time.Duration
+	// a restarted leader numbers the events from 1 again, the follower gets the snapshot then
+	if leader.Seq > status.Seq {
+		lag = leader.Seq - status.Seq
+		if !applied.IsZero() {
+			lagTime = time.Since(applied)
+		}
+	}
+	status.LagEvents = opt.OUint64(lag)
+	status.LagMillis = opt.OInt64(int64(lagTime / time.Millisecond))
+	return status
+}
+
+// applyEvent makes the change of the leader event in s.
+// Deletes do not cascade on the follower, the leader sends the events of the deleted visits first.
+func applyEvent(s *store.Store, e *model.Event) error {
+	deleted := e.Type == store.EventDeleted.String()
+	created := e.Type == store.EventCreated.String()
+	if !deleted && !created && e.Type != store.EventUpdated.String() {
+		return ErrEventType
+	}
+	switch e.Entity {
+	case store.EntityUsers:
+		if deleted {
+			return s.DeleteUser(e.ID)
+		}
+		var user model.User
+		if err := easyjson.Unmarshal(e.After, &user); err != nil {
+			return err
+		}
+		if created {
+			return s.AddUser(user)
+		}
+		user.ID = opt.Int32{}
+		return s.UpdateUserByID(e.ID, user)
+	case store.EntityLocations:
+		if deleted {
+			return s.DeleteLocation(e.ID)
+		}
+		var location model.Location
+		if err := easyjson.Unmarshal(e.After, &location); err != nil {
+			return err
+		}
+		if created {
+			return s.AddLocation(location)
+		}
+		location.ID = opt.Int32{}
+		return s.UpdateLocationByID(e.ID, location)
+	case store.EntityVisits:
+		if deleted {
+			return s.DeleteVisit(e.ID)
+		}
+		var visit model.Visit
+		if err := easyjson.Unmarshal(e.After, &visit); err != nil {
+			return err
+		}
+		if created {
+			return s.AddVisit(visit)
+		}
+		visit.ID = opt.Int32{}
+		return s.UpdateVisitByID(e.ID, visit)
+	}
+	return ErrEventType
+}
+
+// readOnly rejects the writes of the follower
+func readOnly(ctx *fasthttp.RequestCtx) bool {
+	if following == nil || ctx.IsGet() {
+		return false
+	}
+	writeError(ctx, http.StatusForbidden, ErrReadOnly)
+	return true
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/la0rg/highloadcup/config"
+	"github.com/la0rg/highloadcup/model"
+	"github.com/la0rg/highloadcup/store"
+	"github.com/valyala/fasthttp"
+)
+
+// startLeader serves the handlers of the server with a new store as the leader,
+// the returned function stops the server
+func startLeader(t *testing.T) (string, func()) {
+	c, err := config.Load("highloadcup", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = c
+	dataStore = store.NewStore()
+	dataStore.SetCascadePolicy(store.CascadeDelete)
+	dataStore.EnableEvents(cfg.EventsBuffer)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go fasthttp.Serve(ln, serve(newRouter()))
+	return "http://" + ln.Addr().String(), func() { ln.Close() }
+}
+
+// post sends the request to the leader and fails the test if it is not accepted
+func post(t *testing.T, url, body string) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST %s %s: status %d", url, body, resp.StatusCode)
+	}
+}
+
+func TestReplica_FollowsLeader(t *testing.T) {
+	leader, stopLeader := startLeader(t)
+	defer stopLeader()
+	post(t, leader+"/users/new", `{"id": 1, "email": "a@b.c", "first_name": "a", "last_name": "b", "gender": "m", "birth_date": 0}`)
+	post(t, leader+"/locations/new", `{"id": 1, "place": "p", "country": "c", "city": "c", "distance": 1}`)
+	post(t, leader+"/visits/new", `{"id": 1, "user": 1, "location": 1, "visited_at": 1, "mark": 3}`)
+	post(t, leader+"/visits/new", `{"id": 2, "user": 1, "location": 1, "visited_at": 2, "mark": 4}`)
+
+	follower := store.NewStore()
+	follower.SetCascadePolicy(store.CascadeOrphan)
+	r := newReplica(leader, follower)
+	if err := r.resync(); err != nil {
+		t.Fatal(err)
+	}
+	if seq := r.lastSeq(); seq != 4 {
+		t.Fatalf("expected the snapshot at event 4, got %d", seq)
+	}
+	if _, ok := follower.GetVisitByID(2); !ok {
+		t.Fatal("visit of the snapshot is not loaded")
+	}
+	done := make(chan struct{})
+	go func() {
+		r.run()
+		close(done)
+	}()
+	defer func() {
+		r.stop()
+		<-done
+	}()
+
+	post(t, leader+"/users/new", `{"id": 2, "email": "b@b.c", "first_name": "a", "last_name": "b", "gender": "f", "birth_date": 0}`)
+	post(t, leader+"/visits/new", `{"id": 3, "user": 2, "location": 1, "visited_at": 3, "mark": 3}`)
+	post(t, leader+"/locations/1", `{"city": "x"}`)
+	post(t, leader+"/visits/3", `{"mark": 5}`)
+	// the leader sends the deletes of the visits before the delete of the user
+	req, err := http.NewRequest(http.MethodDelete, leader+"/users/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE /users/1: status %d", resp.StatusCode)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for r.lastSeq() < dataStore.LastEventSeq() {
+		if time.Now().After(deadline) {
+			t.Fatalf("follower applied %d of %d events", r.lastSeq(), dataStore.LastEventSeq())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, ok := follower.GetUserByID(2); !ok {
+		t.Error("created user is not replicated")
+	}
+	if l, _ := follower.GetLocationByID(1); l.City.V != "x" {
+		t.Errorf("location update is not replicated: %v", l)
+	}
+	if v, ok := follower.GetVisitByID(3); !ok || v.Mark.V != 5 || v.UserID.V != 2 {
+		t.Errorf("expected the updated visit 3, got %v", v)
+	}
+	if _, ok := follower.GetUserByID(1); ok {
+		t.Error("deleted user is kept")
+	}
+	for id := int32(1); id <= 2; id++ {
+		if _, ok := follower.GetVisitByID(id); ok {
+			t.Errorf("visit %d of the deleted user is kept", id)
+		}
+	}
+	avg, _ := follower.GetLocationAvg(1, nil, nil, nil, nil, nil)
+	if expected, _ := dataStore.GetLocationAvg(1, nil, nil, nil, nil, nil); avg != expected {
+		t.Errorf("expected avg %v of the leader, got %v", expected, avg)
+	}
+}
+
+func TestApplyEvent(t *testing.T) {
+	s := store.NewStore()
+	after := []byte(`{"id": 1, "email": "a@b.c", "first_name": "a", "last_name": "b", "gender": "m", "birth_date": 0}`)
+	events := []model.Event{
+		{Type: store.EventCreated.String(), Entity: store.EntityUsers, ID: 1, After: after},
+		{Type: store.EventUpdated.String(), Entity: store.EntityUsers, ID: 1, After: []byte(`{"id": 1, "first_name": "x"}`)},
+	}
+	for i := range events {
+		if err := applyEvent(s, &events[i]); err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+	}
+	if u, ok := s.GetUserByID(1); !ok || u.FirstName.V != "x" || u.LastName.V != "b" {
+		t.Errorf("expected the updated user, got %v", u)
+	}
+	if err := applyEvent(s, &model.Event{Type: store.EventDeleted.String(), Entity: store.EntityUsers, ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.GetUserByID(1); ok {
+		t.Error("deleted user is kept")
+	}
+	if err := applyEvent(s, &model.Event{Type: "moved", Entity: store.EntityUsers, ID: 1}); err != ErrEventType {
+		t.Errorf("expected ErrEventType, got %v", err)
+	}
+	if err := applyEvent(s, &model.Event{Type: store.EventDeleted.String(), Entity: "sights", ID: 1}); err != ErrEventType {
+		t.Errorf("expected ErrEventType, got %v", err)
+	}
+}
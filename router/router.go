@@ -0,0 +1,262 @@
+// Package router dispatches fasthttp requests by method and path.
+//
+// Routes are kept in a tree of path segments. A segment is either static ("users")
+// or a parameter ("{id:int}", "{name}"). Static segments have priority over parameters,
+// so "/users/new" and "/users/{id:int}" can be registered together.
+// Matching does not allocate, int parameters are parsed into int32 user values
+// and string parameters are stored as []byte pointing into the request path.
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// maxParams is the maximum number of parameters in a route
+const maxParams = 4
+
+type segmentKind byte
+
+const (
+	staticSegment segmentKind = iota
+	intParam
+	stringParam
+)
+
+type node struct {
+	kind     segmentKind
+	segment  string // static segment or parameter name
+	static   []*node
+	param    *node
+	handlers map[string]fasthttp.RequestHandler
+	allow    string
+}
+
+// Router is a fasthttp.RequestHandler provider
+type Router struct {
+	root node
+	// NotFound is called when there is no route for the path (404 by default)
+	NotFound fasthttp.RequestHandler
+	// MethodNotAllowed is called when the path exists, but not for the method (405 by default)
+	MethodNotAllowed fasthttp.RequestHandler
+}
+
+// New creates an empty router
+func New() *Router {
+	return &Router{
+		NotFound:         notFound,
+		MethodNotAllowed: methodNotAllowed,
+	}
+}
+
+// GET registers handler for GET requests to pattern
+func (r *Router) GET(pattern string, h fasthttp.RequestHandler) {
+	r.Handle("GET", pattern, h)
+}
+
+// POST registers handler for POST requests to pattern
+func (r *Router) POST(pattern string, h fasthttp.RequestHandler) {
+	r.Handle("POST", pattern, h)
+}
+
+// DELETE registers handler for DELETE requests to pattern
+func (r *Router) DELETE(pattern string, h fasthttp.RequestHandler) {
+	r.Handle("DELETE", pattern, h)
+}
+
+// Handle registers handler for the method and pattern.
+// It panics on invalid patterns and on conflicting routes.
+func (r *Router) Handle(method, pattern string, h fasthttp.RequestHandler) {
+	if !strings.HasPrefix(pattern, "/") {
+		panic(fmt.Sprintf("router: pattern %q should start with /", pattern))
+	}
+	n := &r.root
+	params := 0
+	if pattern != "/" {
+		for _, segment := range strings.Split(pattern[1:], "/") {
+			n = n.child(pattern, segment)
+			if n.kind != staticSegment {
+				params++
+			}
+		}
+	}
+	if params > maxParams {
+		panic(fmt.Sprintf("router: pattern %q has more than %d parameters", pattern, maxParams))
+	}
+	if n.handlers == nil {
+		n.handlers = make(map[string]fasthttp.RequestHandler)
+	}
+	if _, ok := n.handlers[method]; ok {
+		panic(fmt.Sprintf("router: %s %s is already registered", method, pattern))
+	}
+	n.handlers[method] = h
+
+	methods := make([]string, 0, len(n.handlers))
+	for m := range n.handlers {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	n.allow = strings.Join(methods, ", ")
+}
+
+// child finds or creates the child node for the segment of the pattern
+func (n *node) child(pattern, segment string) *node {
+	if segment == "" {
+		panic(fmt.Sprintf("router: pattern %q has an empty segment", pattern))
+	}
+	if segment[0] != '{' {
+		for _, c := range n.static {
+			if c.segment == segment {
+				return c
+			}
+		}
+		c := &node{kind: staticSegment, segment: segment}
+		n.static = append(n.static, c)
+		return c
+	}
+
+	if segment[len(segment)-1] != '}' {
+		panic(fmt.Sprintf("router: pattern %q has unclosed parameter", pattern))
+	}
+	name, kind := segment[1:len(segment)-1], stringParam
+	if i := strings.IndexByte(name, ':'); i != -1 {
+		switch name[i+1:] {
+		case "int":
+			kind = intParam
+		case "string":
+		default:
+			panic(fmt.Sprintf("router: pattern %q has unknown parameter type %q", pattern, name[i+1:]))
+		}
+		name = name[:i]
+	}
+	if n.param == nil {
+		n.param = &node{kind: kind, segment: name}
+	} else if n.param.kind != kind || n.param.segment != name {
+		panic(fmt.Sprintf("router: parameter %q of pattern %q conflicts with an existing route", segment, pattern))
+	}
+	return n.param
+}
+
+// params keeps the matched parameters on the stack during the lookup
+type params struct {
+	n      int
+	nodes  [maxParams]*node
+	values [maxParams][]byte
+	ints   [maxParams]int32
+}
+
+// match finds the node for the rest of the path that starts with '/'
+func (n *node) match(path []byte, p *params) *node {
+	if len(path) == 0 {
+		if n.handlers == nil {
+			return nil
+		}
+		return n
+	}
+	path = path[1:]
+	end := 0
+	for end < len(path) && path[end] != '/' {
+		end++
+	}
+	segment, rest := path[:end], path[end:]
+
+	for _, c := range n.static {
+		if c.segment == string(segment) {
+			if found := c.match(rest, p); found != nil {
+				return found
+			}
+			break
+		}
+	}
+	if n.param == nil || len(segment) == 0 {
+		return nil
+	}
+	if n.param.kind == intParam {
+		i, ok := parseInt32(segment)
+		if !ok {
+			return nil
+		}
+		p.ints[p.n] = i
+	}
+	p.nodes[p.n] = n.param
+	p.values[p.n] = segment
+	p.n++
+	if found := n.param.match(rest, p); found != nil {
+		return found
+	}
+	p.n--
+	return nil
+}
+
+// Handler dispatches the request to the registered handler
+func (r *Router) Handler(ctx *fasthttp.RequestCtx) {
+	var p params
+	path := ctx.Path()
+	var n *node
+	if len(path) == 1 && path[0] == '/' {
+		if r.root.handlers != nil {
+			n = &r.root
+		}
+	} else if len(path) > 0 && path[0] == '/' {
+		n = r.root.match(path, &p)
+	}
+	if n == nil {
+		r.NotFound(ctx)
+		return
+	}
+	h, ok := n.handlers[string(ctx.Method())]
+	if !ok {
+		ctx.Response.Header.Set("Allow", n.allow)
+		r.MethodNotAllowed(ctx)
+		return
+	}
+	for i := 0; i < p.n; i++ {
+		if p.nodes[i].kind == intParam {
+			ctx.SetUserValue(p.nodes[i].segment, p.ints[i])
+		} else {
+			ctx.SetUserValue(p.nodes[i].segment, p.values[i])
+		}
+	}
+	h(ctx)
+}
+
+// parseInt32 parses decimal number without allocations
+func parseInt32(b []byte) (int32, bool) {
+	neg := false
+	if len(b) > 0 && b[0] == '-' {
+		neg = true
+		b = b[1:]
+	}
+	if len(b) == 0 {
+		return 0, false
+	}
+	var v int64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		v = v*10 + int64(c-'0')
+		if v > 1<<31 {
+			return 0, false
+		}
+	}
+	if neg {
+		v = -v
+	}
+	if v > 1<<31-1 || v < -1<<31 {
+		return 0, false
+	}
+	return int32(v), true
+}
+
+func notFound(ctx *fasthttp.RequestCtx) {
+	ctx.SetStatusCode(http.StatusNotFound)
+}
+
+func methodNotAllowed(ctx *fasthttp.RequestCtx) {
+	ctx.SetStatusCode(http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,194 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/qiangxue/fasthttp-routing"
+	"github.com/valyala/fasthttp"
+)
+
+func newTestRouter(matched *string) *Router {
+	r := New()
+	handler := func(route string) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			*matched = route
+		}
+	}
+	r.GET("/users/{id:int}", handler("user"))
+	r.GET("/users/{id:int}/visits", handler("visits"))
+	r.POST("/users/new", handler("new user"))
+	r.POST("/users/{id:int}", handler("update user"))
+	r.DELETE("/users/{id:int}", handler("delete user"))
+	r.GET("/locations/top", handler("top"))
+	r.GET("/locations/{id:int}", handler("location"))
+	r.GET("/tags/{name}", handler("tag"))
+	return r
+}
+
+func request(r *Router, method, uri string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(uri)
+	r.Handler(ctx)
+	return ctx
+}
+
+func TestRouter_Handler(t *testing.T) {
+	var matched string
+	r := newTestRouter(&matched)
+	cases := []struct {
+		method, uri string
+		status      int
+		route       string
+		id          interface{}
+	}{
+		{"GET", "/users/1", http.StatusOK, "user", int32(1)},
+		{"GET", "/users/-5", http.StatusOK, "user", int32(-5)},
+		{"GET", "/users/1?fromDate=10", http.StatusOK, "user", int32(1)},
+		{"GET", "/users/2147483647", http.StatusOK, "user", int32(2147483647)},
+		{"GET", "/users/2147483648", http.StatusNotFound, "", nil},
+		{"GET", "/users/1/visits", http.StatusOK, "visits", int32(1)},
+		{"POST", "/users/new", http.StatusOK, "new user", nil},
+		{"POST", "/users/7", http.StatusOK, "update user", int32(7)},
+		{"DELETE", "/users/7", http.StatusOK, "delete user", int32(7)},
+		{"GET", "/locations/top", http.StatusOK, "top", nil},
+		{"GET", "/locations/3", http.StatusOK, "location", int32(3)},
+		{"GET", "/tags/abc", http.StatusOK, "tag", nil},
+		{"GET", "/users/abc", http.StatusNotFound, "", nil},
+		{"GET", "/users/", http.StatusNotFound, "", nil},
+		{"GET", "/users/1/", http.StatusNotFound, "", nil},
+		{"GET", "/users/1/avg", http.StatusNotFound, "", nil},
+		{"GET", "/users", http.StatusNotFound, "", nil},
+		{"GET", "/", http.StatusNotFound, "", nil},
+		{"GET", "/users/new", http.StatusMethodNotAllowed, "", nil},
+		{"PUT", "/users/1", http.StatusMethodNotAllowed, "", nil},
+	}
+	for _, c := range cases {
+		matched = ""
+		ctx := request(r, c.method, c.uri)
+		if ctx.Response.StatusCode() != c.status {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.uri, c.status, ctx.Response.StatusCode())
+		}
+		if matched != c.route {
+			t.Errorf("%s %s: expected route %q, got %q", c.method, c.uri, c.route, matched)
+		}
+		if c.id != nil && ctx.UserValue("id") != c.id {
+			t.Errorf("%s %s: expected id %v, got %v", c.method, c.uri, c.id, ctx.UserValue("id"))
+		}
+	}
+
+	ctx := request(r, "GET", "/tags/abc")
+	if name, ok := ctx.UserValue("name").([]byte); !ok || string(name) != "abc" {
+		t.Errorf("expected name abc, got %v", ctx.UserValue("name"))
+	}
+	ctx = request(r, "PUT", "/users/1")
+	if allow := string(ctx.Response.Header.Peek("Allow")); allow != "DELETE, GET, POST" {
+		t.Errorf("expected Allow: DELETE, GET, POST, got %q", allow)
+	}
+}
+
+func TestRouter_HandleConflicts(t *testing.T) {
+	cases := []struct {
+		method, pattern string
+	}{
+		{"GET", "/users/{id:int}"},
+		{"GET", "/users/{name}"},
+		{"GET", "/users/{uid:int}/visits"},
+		{"GET", "/users//visits"},
+		{"GET", "users"},
+		{"GET", "/users/{id:float}"},
+		{"GET", "/users/{id"},
+	}
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s %s: expected panic", c.method, c.pattern)
+				}
+			}()
+			r := New()
+			r.GET("/users/{id:int}", func(*fasthttp.RequestCtx) {})
+			r.Handle(c.method, c.pattern, func(*fasthttp.RequestCtx) {})
+		}()
+	}
+}
+
+func TestParseInt32(t *testing.T) {
+	cases := []struct {
+		s  string
+		v  int32
+		ok bool
+	}{
+		{"0", 0, true},
+		{"42", 42, true},
+		{"-2147483648", -2147483648, true},
+		{"2147483647", 2147483647, true},
+		{"2147483648", 0, false},
+		{"-2147483649", 0, false},
+		{"99999999999999999999", 0, false},
+		{"", 0, false},
+		{"-", 0, false},
+		{"1a", 0, false},
+		{"+1", 0, false},
+	}
+	for _, c := range cases {
+		v, ok := parseInt32([]byte(c.s))
+		if v != c.v || ok != c.ok {
+			t.Errorf("%q: expected %d, %v, got %d, %v", c.s, c.v, c.ok, v, ok)
+		}
+	}
+}
+
+var benchPaths = []string{"/users/1234", "/users/1234/visits", "/locations/42", "/locations/top"}
+
+func BenchmarkRouter(b *testing.B) {
+	var matched string
+	r := newTestRouter(&matched)
+	ctxs := make([]*fasthttp.RequestCtx, len(benchPaths))
+	for i, path := range benchPaths {
+		ctxs[i] = &fasthttp.RequestCtx{}
+		ctxs[i].Request.Header.SetMethod("GET")
+		ctxs[i].Request.SetRequestURI(path)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Handler(ctxs[i%len(ctxs)])
+	}
+	if matched == "" {
+		b.Fatal("no route matched")
+	}
+}
+
+// BenchmarkFasthttpRouting is the same set of routes served by fasthttp-routing
+func BenchmarkFasthttpRouting(b *testing.B) {
+	var matched string
+	r := routing.New()
+	handler := func(route string) routing.Handler {
+		return func(c *routing.Context) error {
+			matched = route
+			return nil
+		}
+	}
+	r.Get("/users/<id:\\d+>", handler("user"))
+	r.Get("/users/<id:\\d+>/visits", handler("visits"))
+	r.Post("/users/new", handler("new user"))
+	r.Post("/users/<id:\\d+>", handler("update user"))
+	r.Get("/locations/top", handler("top"))
+	r.Get("/locations/<id:\\d+>", handler("location"))
+	ctxs := make([]*fasthttp.RequestCtx, len(benchPaths))
+	for i, path := range benchPaths {
+		ctxs[i] = &fasthttp.RequestCtx{}
+		ctxs[i].Request.Header.SetMethod("GET")
+		ctxs[i].Request.SetRequestURI(path)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.HandleRequest(ctxs[i%len(ctxs)])
+	}
+	if matched == "" {
+		b.Fatal("no route matched")
+	}
+}
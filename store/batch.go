@@ -0,0 +1,90 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/la0rg/highloadcup/model"
+)
+
+// ErrBatchAborted is returned for the operations of an atomic batch that failed on another operation
+var ErrBatchAborted = errors.New("Batch is aborted")
+
+// Op is a create or an update of a single entity in a batch.
+// Exactly one of User, Location and Visit should be set,
+// ID is the id of the updated entity and it is ignored by creates.
+type Op struct {
+	Update   bool
+	ID       int32
+	User     *model.User
+	Location *model.Location
+	Visit    *model.Visit
+}
+
+func (op *Op) record() *walRecord {
+	rec := &walRecord{id: op.ID, user: op.User, location: op.Location, visit: op.Visit}
+	switch {
+	case op.User != nil && op.Update:
+		rec.op = walUpdateUser
+	case op.User != nil:
+		rec.op = walAddUser
+	case op.Location != nil && op.Update:
+		rec.op = walUpdateLocation
+	case op.Location != nil:
+		rec.op = walAddLocation
+	case op.Visit != nil && op.Update:
+		rec.op = walUpdateVisit
+	case op.Visit != nil:
+		rec.op = walAddVisit
+	default:
+		return nil
+	}
+	return rec
+}
+
+// Batch applies the operations and returns their errors (nil for the applied ones).
+// Without atomic flag every operation succeeds or fails on its own.
+// With atomic flag the operations are applied in a single transaction,
+// if one of them fails the rest get ErrBatchAborted.
+func (s *Store) Batch(ops []Op, atomic bool) []error {
+	errs := make([]error, len(ops))
+	if !atomic {
+		for i := range ops {
+			if rec := ops[i].record(); rec != nil {
+				errs[i] = s.commit(rec)
+			} else {
+				errs[i] = ErrRequiredFields
+			}
+		}
+		return errs
+	}
+
+	tx := s.Begin()
+	for i := range ops {
+		rec := ops[i].record()
+		if rec == nil {
+			tx.Rollback()
+			return abortBatch(errs, i, ErrRequiredFields)
+		}
+		tx.recs = append(tx.recs, rec)
+	}
+	err := tx.Commit()
+	if txErr, ok := err.(*TxError); ok {
+		return abortBatch(errs, txErr.Op, txErr.Err)
+	}
+	if err != nil {
+		// the log is failed, none of the operations is applied
+		for i := range errs {
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
+// abortBatch sets err of the failed operation and ErrBatchAborted of the others
+func abortBatch(errs []error, failed int, err error) []error {
+	for i := range errs {
+		errs[i] = ErrBatchAborted
+	}
+	errs[failed] = err
+	return errs
+}
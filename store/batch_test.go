@@ -0,0 +1,68 @@
+package store
+
+import (
+	"math"
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func TestStore_AtomicBatch(t *testing.T) {
+	s := NewStore()
+	addTestEntities(s, 1)
+	s.AddVisit(model.Visit{ID: opt.OInt32(1), UserID: opt.OInt32(1), LocationID: opt.OInt32(1),
+		VisitedAt: opt.OInt64(10), Mark: opt.OUint8(4)})
+
+	location := testLocation(2)
+	ops := []Op{
+		{Update: true, ID: 1, User: &model.User{FirstName: opt.OString("x")}},
+		{Update: true, ID: 1, Visit: &model.Visit{LocationID: opt.OInt32(2), Mark: opt.OUint8(1)}},
+		{Location: &location},
+		{Visit: &model.Visit{ID: opt.OInt32(2), UserID: opt.OInt32(1), LocationID: opt.OInt32(2),
+			VisitedAt: opt.OInt64(20), Mark: opt.OUint8(2)}},
+		// already exists
+		{Visit: &model.Visit{ID: opt.OInt32(1), UserID: opt.OInt32(1), LocationID: opt.OInt32(1),
+			VisitedAt: opt.OInt64(30), Mark: opt.OUint8(2)}},
+	}
+	errs := s.Batch(ops, true)
+	for i, err := range errs {
+		expected := ErrBatchAborted
+		if i == len(errs)-1 {
+			expected = ErrAlreadyExist
+		}
+		if err != expected {
+			t.Errorf("op %d: expected %v, got %v", i, expected, err)
+		}
+	}
+
+	if u, _ := s.GetUserByID(1); u.FirstName.V != "a" {
+		t.Errorf("user update was not reverted: %v", u)
+	}
+	if v, _ := s.GetVisitByID(1); v.LocationID.V != 1 || v.Mark.V != 4 || v.Location == nil || v.Location.ID.V != 1 {
+		t.Errorf("visit update was not reverted: %v", v)
+	}
+	if _, ok := s.GetLocationByID(2); ok {
+		t.Error("location create was not reverted")
+	}
+	if _, ok := s.GetVisitByID(2); ok {
+		t.Error("visit create was not reverted")
+	}
+	visits, _ := s.GetVisitsByUserID(1, nil, nil, nil, nil, nil)
+	if len(visits.Visits) != 1 {
+		t.Errorf("expected 1 user visit, got %v", visits.Visits)
+	}
+	if avg, _ := s.GetLocationAvg(1, nil, nil, nil, nil, nil); math.Abs(avg-4) > 1e-9 {
+		t.Errorf("expected avg 4, got %v", avg)
+	}
+
+	errs = s.Batch(ops[:4], false)
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("op %d: %v", i, err)
+		}
+	}
+	if avg, _ := s.GetLocationAvg(2, nil, nil, nil, nil, nil); math.Abs(avg-1.5) > 1e-9 {
+		t.Errorf("expected avg 1.5, got %v", avg)
+	}
+}
@@ -0,0 +1,305 @@
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+var ErrCorrupted = errors.New("Corrupted binary data")
+
+// maxStringLen protects decoder from allocating huge buffers on corrupted input
+const maxStringLen = 1 << 16
+
+// encoder writes compact binary representation of the model entities.
+// Every entity starts with a bit mask of defined fields
+// followed by the values of the defined fields only.
+// The first error is sticky, so callers may check it once at the end.
+type encoder struct {
+	w   *bufio.Writer
+	crc hash.Hash32
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func newEncoder(w io.Writer) *encoder {
+	return &encoder{
+		w:   bufio.NewWriter(w),
+		crc: crc32.NewIEEE(),
+	}
+}
+
+func (e *encoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	e.crc.Write(b)
+	_, e.err = e.w.Write(b)
+}
+
+func (e *encoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.buf[:], v)
+	e.write(e.buf[:n])
+}
+
+func (e *encoder) varint(v int64) {
+	n := binary.PutVarint(e.buf[:], v)
+	e.write(e.buf[:n])
+}
+
+func (e *encoder) byte(b byte) {
+	e.buf[0] = b
+	e.write(e.buf[:1])
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.write([]byte(s))
+}
+
+// checksum writes crc32 of all the data written so far (not included in the checksum itself)
+func (e *encoder) checksum() {
+	if e.err != nil {
+		return
+	}
+	binary.LittleEndian.PutUint32(e.buf[:4], e.crc.Sum32())
+	_, e.err = e.w.Write(e.buf[:4])
+}
+
+// reset starts a new checksum block
+func (e *encoder) reset() {
+	e.crc.Reset()
+}
+
+func (e *encoder) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+func (e *encoder) user(u *model.User) {
+	e.byte(mask(u.ID.Defined, u.Email.Defined, u.FirstName.Defined, u.LastName.Defined, u.Gender.Defined, u.BirthDate.Defined))
+	e.optInt32(u.ID)
+	e.optString(u.Email)
+	e.optString(u.FirstName)
+	e.optString(u.LastName)
+	e.optString(u.Gender)
+	e.optInt64(u.BirthDate)
+}
+
+func (e *encoder) location(l *model.Location) {
+	e.byte(mask(l.ID.Defined, l.Place.Defined, l.Country.Defined, l.City.Defined, l.Distance.Defined))
+	e.optInt32(l.ID)
+	e.optString(l.Place)
+	e.optString(l.Country)
+	e.optString(l.City)
+	e.optInt32(l.Distance)
+}
+
+func (e *encoder) visit(v *model.Visit) {
+	e.byte(mask(v.ID.Defined, v.LocationID.Defined, v.UserID.Defined, v.VisitedAt.Defined, v.Mark.Defined))
+	e.optInt32(v.ID)
+	e.optInt32(v.LocationID)
+	e.optInt32(v.UserID)
+	e.optInt64(v.VisitedAt)
+	if v.Mark.Defined {
+		e.byte(v.Mark.V)
+	}
+}
+
+func (e *encoder) optInt32(v opt.Int32) {
+	if v.Defined {
+		e.varint(int64(v.V))
+	}
+}
+
+func (e *encoder) optInt64(v opt.Int64) {
+	if v.Defined {
+		e.varint(v.V)
+	}
+}
+
+func (e *encoder) optString(v opt.String) {
+	if v.Defined {
+		e.string(v.V)
+	}
+}
+
+func mask(defined ...bool) byte {
+	var m byte
+	for i, d := range defined {
+		if d {
+			m |= 1 << uint(i)
+		}
+	}
+	return m
+}
+
+// decoder reads data written by encoder
+type decoder struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+	buf [4]byte
+	err error
+}
+
+func newDecoder(r io.Reader) *decoder {
+	return &decoder{
+		r:   bufio.NewReader(r),
+		crc: crc32.NewIEEE(),
+	}
+}
+
+// ReadByte makes decoder an io.ByteReader for binary.ReadUvarint
+func (d *decoder) ReadByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	d.buf[0] = b
+	d.crc.Write(d.buf[:1])
+	return b, nil
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+	}
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	b, err := d.ReadByte()
+	if err != nil {
+		d.fail(err)
+	}
+	return b
+}
+
+func (d *decoder) read(b []byte) {
+	if d.err != nil {
+		return
+	}
+	_, err := io.ReadFull(d.r, b)
+	if err != nil {
+		d.fail(err)
+		return
+	}
+	d.crc.Write(b)
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if n > maxStringLen {
+		d.fail(ErrCorrupted)
+		return ""
+	}
+	b := make([]byte, n)
+	d.read(b)
+	return string(b)
+}
+
+// checksum reads crc32 written by encoder.checksum and compares it with the calculated one
+func (d *decoder) checksum() {
+	if d.err != nil {
+		return
+	}
+	sum := d.crc.Sum32()
+	_, err := io.ReadFull(d.r, d.buf[:4])
+	if err != nil {
+		d.fail(err)
+		return
+	}
+	if binary.LittleEndian.Uint32(d.buf[:4]) != sum {
+		d.fail(ErrCorrupted)
+	}
+}
+
+func (d *decoder) reset() {
+	d.crc.Reset()
+}
+
+func (d *decoder) user(u *model.User) {
+	m := d.byte()
+	u.ID = d.optInt32(m, 0)
+	u.Email = d.optString(m, 1)
+	u.FirstName = d.optString(m, 2)
+	u.LastName = d.optString(m, 3)
+	u.Gender = d.optString(m, 4)
+	u.BirthDate = d.optInt64(m, 5)
+}
+
+func (d *decoder) location(l *model.Location) {
+	m := d.byte()
+	l.ID = d.optInt32(m, 0)
+	l.Place = d.optString(m, 1)
+	l.Country = d.optString(m, 2)
+	l.City = d.optString(m, 3)
+	l.Distance = d.optInt32(m, 4)
+}
+
+func (d *decoder) visit(v *model.Visit) {
+	m := d.byte()
+	v.ID = d.optInt32(m, 0)
+	v.LocationID = d.optInt32(m, 1)
+	v.UserID = d.optInt32(m, 2)
+	v.VisitedAt = d.optInt64(m, 3)
+	if m&(1<<4) != 0 {
+		v.Mark = opt.OUint8(d.byte())
+	}
+}
+
+func (d *decoder) optInt32(m byte, bit uint) opt.Int32 {
+	if m&(1<<bit) == 0 {
+		return opt.Int32{}
+	}
+	return opt.OInt32(int32(d.varint()))
+}
+
+func (d *decoder) optInt64(m byte, bit uint) opt.Int64 {
+	if m&(1<<bit) == 0 {
+		return opt.Int64{}
+	}
+	return opt.OInt64(d.varint())
+}
+
+func (d *decoder) optString(m byte, bit uint) opt.String {
+	if m&(1<<bit) == 0 {
+		return opt.String{}
+	}
+	return opt.OString(d.string())
+}
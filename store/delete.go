@@ -0,0 +1,176 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/la0rg/highloadcup/model"
+)
+
+// CascadePolicy defines what happens to the visits of a deleted user or location
+type CascadePolicy byte
+
+const (
+	// CascadeReject does not allow to delete a user or a location that has visits
+	CascadeReject CascadePolicy = iota
+	// CascadeDelete deletes the visits together with the user or the location
+	CascadeDelete
+	// CascadeOrphan keeps the visits, but clears their back-pointers.
+	// Entity created again with the same id gets the visits back.
+	CascadeOrphan
+)
+
+var (
+	ErrHasVisits     = errors.New("Entity has visits")
+	ErrCascadePolicy = errors.New("Unknown cascade policy")
+)
+
+// ParseCascadePolicy converts reject|cascade|orphan into CascadePolicy
+func ParseCascadePolicy(s string) (CascadePolicy, error) {
+	switch s {
+	case "reject":
+		return CascadeReject, nil
+	case "cascade":
+		return CascadeDelete, nil
+	case "orphan":
+		return CascadeOrphan, nil
+	}
+	return 0, ErrCascadePolicy
+}
+
+// SetCascadePolicy defines how DeleteUser and DeleteLocation treat dependent visits.
+// It should be called before the store is used concurrently.
+func (s *Store) SetCascadePolicy(policy CascadePolicy) {
+	s.cascade = policy
+}
+
+// CascadePolicy returns the policy set by SetCascadePolicy
+func (s *Store) CascadePolicy() CascadePolicy {
+	return s.cascade
+}
+
+// DeleteUser removes user with id from the store
+// if user does not exist returns error
+func (s *Store) DeleteUser(id int32) error {
+	return s.commit(&walRecord{op: walDeleteUser, id: id, cascade: s.cascade})
+}
+
+func (s *Store) deleteUser(id int32, policy CascadePolicy) error {
+	if err := s.checkDeleteUser(id, policy); err != nil {
+		return err
+	}
+	vi := s.visitsByUserID.get(id)
+	if vi != nil && vi.Len() > 0 {
+		switch policy {
+		case CascadeDelete:
+			for _, visit := range vi.All() {
+				if vl := s.visitsByLocationID.get(visit.LocationID.V); vl != nil {
+					vl.Remove(visit)
+				}
+				s.removeVisit(visit)
+			}
+			vi = nil
+		case CascadeOrphan:
+			vi.ApplyToAll(func(visit *model.Visit) {
+				visit.User = nil
+			})
+		}
+	} else {
+		vi = nil
+	}
+	s.visitsByUserID.set(id, vi)
+	s.usersByID.set(id, nil)
+	return nil
+}
+
+// checkDeleteUser returns the error deleteUser fails with
+func (s *Store) checkDeleteUser(id int32, policy CascadePolicy) error {
+	if s.usersByID.get(id) == nil {
+		return ErrDoesNotExist
+	}
+	if vi := s.visitsByUserID.get(id); policy == CascadeReject && vi != nil && vi.Len() > 0 {
+		return ErrHasVisits
+	}
+	return nil
+}
+
+// DeleteLocation removes location with id from the store
+// if location does not exist returns error
+func (s *Store) DeleteLocation(id int32) error {
+	return s.commit(&walRecord{op: walDeleteLocation, id: id, cascade: s.cascade})
+}
+
+func (s *Store) deleteLocation(id int32, policy CascadePolicy) error {
+	if err := s.checkDeleteLocation(id, policy); err != nil {
+		return err
+	}
+	vl := s.visitsByLocationID.get(id)
+	if vl != nil && vl.Len() > 0 {
+		switch policy {
+		case CascadeDelete:
+			for _, visit := range vl.All() {
+				if vi := s.visitsByUserID.get(visit.UserID.V); vi != nil {
+					vi.Remove(visit)
+				}
+				s.removeVisit(visit)
+			}
+			vl = nil
+		case CascadeOrphan:
+			vl.ApplyToAll(func(visit *model.Visit) {
+				visit.Location = nil
+			})
+		}
+	} else {
+		vl = nil
+	}
+	s.visitsByLocationID.set(id, vl)
+	s.unindexLocation(s.locationsByID.get(id))
+	s.locationsByID.set(id, nil)
+	return nil
+}
+
+// checkDeleteLocation returns the error deleteLocation fails with
+func (s *Store) checkDeleteLocation(id int32, policy CascadePolicy) error {
+	if s.locationsByID.get(id) == nil {
+		return ErrDoesNotExist
+	}
+	if vl := s.visitsByLocationID.get(id); policy == CascadeReject && vl != nil && vl.Len() > 0 {
+		return ErrHasVisits
+	}
+	return nil
+}
+
+// DeleteVisit removes visit with id from the store
+// if visit does not exist returns error
+func (s *Store) DeleteVisit(id int32) error {
+	return s.commit(&walRecord{op: walDeleteVisit, id: id})
+}
+
+func (s *Store) deleteVisit(id int32) error {
+	if err := s.checkDeleteVisit(id); err != nil {
+		return err
+	}
+	v := s.visitsByID.get(id)
+	if vl := s.visitsByLocationID.get(v.LocationID.V); vl != nil {
+		vl.Remove(v)
+	}
+	if vi := s.visitsByUserID.get(v.UserID.V); vi != nil {
+		vi.Remove(v)
+	}
+	s.removeVisit(v)
+	return nil
+}
+
+// checkDeleteVisit returns the error deleteVisit fails with
+func (s *Store) checkDeleteVisit(id int32) error {
+	if s.visitsByID.get(id) == nil {
+		return ErrDoesNotExist
+	}
+	return nil
+}
+
+// removeVisit drops visit from visitsByID, indexes should be updated by the caller
+func (s *Store) removeVisit(visit *model.Visit) {
+	s.visitsByID.set(visit.ID.V, nil)
+	visit.User = nil
+	visit.Location = nil
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+// newDeleteTestStore is newTxTestStore with visit 11 of user 2 at location 2
+func newDeleteTestStore(policy CascadePolicy) *Store {
+	s := newTxTestStore()
+	s.SetCascadePolicy(policy)
+	s.AddVisit(model.Visit{ID: opt.OInt32(11), UserID: opt.OInt32(2), LocationID: opt.OInt32(2),
+		VisitedAt: opt.OInt64(1), Mark: opt.OUint8(3)})
+	return s
+}
+
+func visitCount(vi *VisitIndex) int {
+	if vi == nil {
+		return 0
+	}
+	return vi.Len()
+}
+
+func TestStore_DeletePolicies(t *testing.T) {
+	// user 1 and location 1 have visits 1-10
+	entities := []struct {
+		name string
+		// delete deletes entity 1, recreate adds it again
+		delete, recreate func(s *Store) error
+		exists           func(s *Store) bool
+		// own and other are the indexes of the visits by the entity and by the other entity
+		own, other func(s *Store, id int32) *VisitIndex
+		linked     func(v *model.Visit) bool
+	}{
+		{
+			name:     "user",
+			delete:   func(s *Store) error { return s.DeleteUser(1) },
+			recreate: func(s *Store) error { return s.AddUser(testUser(1)) },
+			exists:   func(s *Store) bool { _, ok := s.GetUserByID(1); return ok },
+			own:      func(s *Store, id int32) *VisitIndex { return s.visitsByUserID.get(id) },
+			other:    func(s *Store, id int32) *VisitIndex { return s.visitsByLocationID.get(id) },
+			linked:   func(v *model.Visit) bool { return v.User != nil },
+		},
+		{
+			name:     "location",
+			delete:   func(s *Store) error { return s.DeleteLocation(1) },
+			recreate: func(s *Store) error { return s.AddLocation(testLocation(1)) },
+			exists:   func(s *Store) bool { _, ok := s.GetLocationByID(1); return ok },
+			own:      func(s *Store, id int32) *VisitIndex { return s.visitsByLocationID.get(id) },
+			other:    func(s *Store, id int32) *VisitIndex { return s.visitsByUserID.get(id) },
+			linked:   func(v *model.Visit) bool { return v.Location != nil },
+		},
+	}
+	for _, e := range entities {
+		s := newDeleteTestStore(CascadeReject)
+		if err := e.delete(s); err != ErrHasVisits {
+			t.Errorf("reject %s: expected ErrHasVisits, got %v", e.name, err)
+		}
+		if !e.exists(s) || visitCount(e.own(s, 1)) != 10 {
+			t.Errorf("reject %s: entity or its visits are deleted", e.name)
+		}
+
+		s = newDeleteTestStore(CascadeDelete)
+		if err := e.delete(s); err != nil {
+			t.Fatalf("cascade %s: %v", e.name, err)
+		}
+		if e.exists(s) {
+			t.Errorf("cascade %s: entity is kept", e.name)
+		}
+		if n := visitCount(e.own(s, 1)) + visitCount(e.other(s, 1)); n != 0 {
+			t.Errorf("cascade %s: %d visits are kept in the indexes", e.name, n)
+		}
+		for id := int32(1); id <= 10; id++ {
+			if _, ok := s.GetVisitByID(id); ok {
+				t.Errorf("cascade %s: visit %d is kept", e.name, id)
+			}
+		}
+		if _, ok := s.GetVisitByID(11); !ok || visitCount(e.own(s, 2)) != 1 || visitCount(e.other(s, 2)) != 1 {
+			t.Errorf("cascade %s: visit of the other entity is deleted", e.name)
+		}
+
+		s = newDeleteTestStore(CascadeOrphan)
+		if err := e.delete(s); err != nil {
+			t.Fatalf("orphan %s: %v", e.name, err)
+		}
+		if e.exists(s) {
+			t.Errorf("orphan %s: entity is kept", e.name)
+		}
+		for id := int32(1); id <= 10; id++ {
+			if v, ok := s.GetVisitByID(id); !ok || e.linked(v) {
+				t.Errorf("orphan %s: expected visit %d without the entity, got %+v", e.name, id, v)
+			}
+		}
+		if visitCount(e.other(s, 1)) != 10 {
+			t.Errorf("orphan %s: visits are removed from the index of the other entity", e.name)
+		}
+		if err := e.recreate(s); err != nil {
+			t.Fatalf("orphan %s: %v", e.name, err)
+		}
+		for id := int32(1); id <= 10; id++ {
+			if v, _ := s.GetVisitByID(id); !e.linked(v) {
+				t.Errorf("orphan %s: visit %d is not relinked", e.name, id)
+			}
+		}
+		if visitCount(e.own(s, 1)) != 10 {
+			t.Errorf("orphan %s: expected 10 visits of the recreated entity, got %d", e.name, visitCount(e.own(s, 1)))
+		}
+
+		if err := e.delete(NewStore()); err != ErrDoesNotExist {
+			t.Errorf("%s: expected ErrDoesNotExist, got %v", e.name, err)
+		}
+	}
+}
+
+func TestStore_DeleteVisit(t *testing.T) {
+	s := newDeleteTestStore(CascadeReject)
+	if err := s.DeleteVisit(11); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.GetVisitByID(11); ok {
+		t.Error("visit is kept")
+	}
+	if visitCount(s.visitsByUserID.get(2))+visitCount(s.visitsByLocationID.get(2)) != 0 {
+		t.Error("visit is kept in the indexes")
+	}
+	// the user and the location have no visits anymore
+	if err := s.DeleteUser(2); err != nil {
+		t.Errorf("expected user without visits deleted, got %v", err)
+	}
+	if err := s.DeleteLocation(2); err != nil {
+		t.Errorf("expected location without visits deleted, got %v", err)
+	}
+	if err := s.DeleteVisit(11); err != ErrDoesNotExist {
+		t.Errorf("expected ErrDoesNotExist, got %v", err)
+	}
+}
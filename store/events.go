@@ -0,0 +1,348 @@
+package store
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson"
+)
+
+var (
+	ErrEventsDisabled = errors.New("Events are disabled")
+	ErrEventsLost     = errors.New("Events after the sequence number are not kept anymore")
+)
+
+// EventType is the kind of the entity change
+type EventType byte
+
+const (
+	EventCreated EventType = iota + 1
+	EventUpdated
+	EventDeleted
+)
+
+var eventTypeNames = [...]string{"", "created", "updated", "deleted"}
+
+func (t EventType) String() string {
+	if int(t) < len(eventTypeNames) {
+		return eventTypeNames[t]
+	}
+	return ""
+}
+
+// Entities of the events
+const (
+	EntityUsers     = "users"
+	EntityLocations = "locations"
+	EntityVisits    = "visits"
+)
+
+// maxEventsRead is the maximum number of the events returned by Subscription.Next
+const maxEventsRead = 1000
+
+// Event is a committed change of a single entity.
+// Events are numbered by Seq in the order of the commits starting from 1.
+type Event struct {
+	Seq uint64
+	// Time is the time of the commit
+	Time   time.Time
+	Type   EventType
+	Entity string
+	ID     int32
+	// Before is the entity before the change (nil for created),
+	// After is the entity after the change (nil for deleted).
+	// They are copies of *model.User, *model.Location or *model.Visit.
+	Before easyjson.Marshaler
+	After  easyjson.Marshaler
+	// Changed lists json names of the updated fields
+	Changed []string
+}
+
+// feed keeps the last events in a ring, event with seq is at seq % len(events)
+type feed struct {
+	mx     sync.Mutex
+	events []Event
+	seq    uint64
+	// wake is closed and replaced by every publish
+	wake chan struct{}
+}
+
+// EnableEvents makes the store number the committed changes
+// and keep the last size of them for the subscribers.
+// It should be called before the store is used concurrently.
+func (s *Store) EnableEvents(size int) {
+	s.feed = &feed{events: make([]Event, size), wake: make(chan struct{})}
+}
+
+// LastEventSeq returns the sequence number of the last event, 0 if there are none
+func (s *Store) LastEventSeq() uint64 {
+	if s.feed == nil {
+		return 0
+	}
+	s.feed.mx.Lock()
+	defer s.feed.mx.Unlock()
+	return s.feed.seq
+}
+
+func (f *feed) publish(events []Event) {
+	now := time.Now()
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	for i := range events {
+		f.seq++
+		events[i].Seq = f.seq
+		events[i].Time = now
+		f.events[f.seq%uint64(len(f.events))] = events[i]
+	}
+	close(f.wake)
+	f.wake = make(chan struct{})
+}
+
+// read returns up to max events after since and the channel closed by the next publish
+func (f *feed) read(since uint64, max int) ([]Event, <-chan struct{}, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	oldest := uint64(1)
+	if f.seq > uint64(len(f.events)) {
+		oldest = f.seq - uint64(len(f.events)) + 1
+	}
+	// since from the future is left from the previous run of the process
+	if since+1 < oldest || since > f.seq {
+		return nil, nil, ErrEventsLost
+	}
+	n := f.seq - since
+	if n > uint64(max) {
+		n = uint64(max)
+	}
+	events := make([]Event, n)
+	for i := range events {
+		events[i] = f.events[(since+1+uint64(i))%uint64(len(f.events))]
+	}
+	return events, f.wake, nil
+}
+
+// Subscription reads the events in order, it is not safe for concurrent use
+type Subscription struct {
+	f    *feed
+	last uint64
+}
+
+// Subscribe starts reading the events after since, use LastEventSeq to get only the new ones.
+// ErrEventsLost is returned if some of them are not kept anymore.
+func (s *Store) Subscribe(since uint64) (*Subscription, error) {
+	if s.feed == nil {
+		return nil, ErrEventsDisabled
+	}
+	_, _, err := s.feed.read(since, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Subscription{f: s.feed, last: since}, nil
+}
+
+// Next returns the events after the previously returned ones waiting for them up to timeout,
+// no events mean the timeout. ErrEventsLost is returned if the subscriber is too slow for the ring.
+func (sub *Subscription) Next(timeout time.Duration) ([]Event, error) {
+	events, wake, err := sub.f.read(sub.last, maxEventsRead)
+	if err == nil && len(events) == 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-wake:
+		case <-timer.C:
+			return nil, nil
+		}
+		events, _, err = sub.f.read(sub.last, maxEventsRead)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > 0 {
+		sub.last = events[len(events)-1].Seq
+	}
+	return events, nil
+}
+
+// applyRecord applies rec and appends its events to events (if not nil), the caller holds the locks of rec
+func (s *Store) applyRecord(rec *walRecord, events *[]Event) error {
+	if events == nil {
+		return s.apply(rec)
+	}
+	if rec.op == walTx {
+		_, err := s.applyTx(rec.tx, events)
+		return err
+	}
+	n := len(*events)
+	*events = s.appendChanges(*events, rec)
+	err := s.apply(rec)
+	if err != nil {
+		*events = (*events)[:n]
+		return err
+	}
+	kept := (*events)[:n]
+	for i := n; i < len(*events); i++ {
+		e := (*events)[i]
+		s.completeChange(&e)
+		// updates that change nothing are not published
+		if e.Type == EventUpdated && len(e.Changed) == 0 {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	*events = kept
+	return nil
+}
+
+// appendChanges appends the events of rec with the entities before the change
+func (s *Store) appendChanges(events []Event, rec *walRecord) []Event {
+	switch rec.op {
+	case walAddUser:
+		return append(events, Event{Type: EventCreated, Entity: EntityUsers, ID: rec.user.ID.V})
+	case walAddLocation:
+		return append(events, Event{Type: EventCreated, Entity: EntityLocations, ID: rec.location.ID.V})
+	case walAddVisit:
+		return append(events, Event{Type: EventCreated, Entity: EntityVisits, ID: rec.visit.ID.V})
+	case walUpdateUser:
+		return append(events, Event{Type: EventUpdated, Entity: EntityUsers, ID: rec.id, Before: s.userCopy(rec.id)})
+	case walUpdateLocation:
+		return append(events, Event{Type: EventUpdated, Entity: EntityLocations, ID: rec.id, Before: s.locationCopy(rec.id)})
+	case walUpdateVisit:
+		return append(events, Event{Type: EventUpdated, Entity: EntityVisits, ID: rec.id, Before: s.visitCopy(rec.id)})
+	case walDeleteVisit:
+		return append(events, Event{Type: EventDeleted, Entity: EntityVisits, ID: rec.id, Before: s.visitCopy(rec.id)})
+	case walDeleteUser:
+		if rec.cascade == CascadeDelete {
+			events = s.appendVisitDeletes(events, s.visitsByUserID.get(rec.id))
+		}
+		return append(events, Event{Type: EventDeleted, Entity: EntityUsers, ID: rec.id, Before: s.userCopy(rec.id)})
+	case walDeleteLocation:
+		if rec.cascade == CascadeDelete {
+			events = s.appendVisitDeletes(events, s.visitsByLocationID.get(rec.id))
+		}
+		return append(events, Event{Type: EventDeleted, Entity: EntityLocations, ID: rec.id, Before: s.locationCopy(rec.id)})
+	}
+	return events
+}
+
+// appendVisitDeletes adds the events of the visits deleted together with their user or location
+func (s *Store) appendVisitDeletes(events []Event, vi *VisitIndex) []Event {
+	if vi == nil {
+		return events
+	}
+	vi.ApplyToAll(func(visit *model.Visit) {
+		events = append(events, Event{Type: EventDeleted, Entity: EntityVisits, ID: visit.ID.V, Before: s.visitCopy(visit.ID.V)})
+	})
+	return events
+}
+
+// completeChange sets the entity after the applied change
+func (s *Store) completeChange(e *Event) {
+	if e.Type == EventDeleted {
+		return
+	}
+	switch e.Entity {
+	case EntityUsers:
+		after := s.userCopy(e.ID)
+		e.After = after
+		if before, ok := e.Before.(*model.User); ok {
+			e.Changed = changedUserFields(before, after)
+		}
+	case EntityLocations:
+		after := s.locationCopy(e.ID)
+		e.After = after
+		if before, ok := e.Before.(*model.Location); ok {
+			e.Changed = changedLocationFields(before, after)
+		}
+	case EntityVisits:
+		after := s.visitCopy(e.ID)
+		e.After = after
+		if before, ok := e.Before.(*model.Visit); ok {
+			e.Changed = changedVisitFields(before, after)
+		}
+	}
+}
+
+// copies are nil for missing entities, the mutations of them fail and their events are dropped
+
+func (s *Store) userCopy(id int32) *model.User {
+	u := s.usersByID.get(id)
+	if u == nil {
+		return nil
+	}
+	c := *u
+	return &c
+}
+
+func (s *Store) locationCopy(id int32) *model.Location {
+	l := s.locationsByID.get(id)
+	if l == nil {
+		return nil
+	}
+	c := *l
+	return &c
+}
+
+// visitCopy copies the fields of the visit without the links
+func (s *Store) visitCopy(id int32) *model.Visit {
+	v := s.visitsByID.get(id)
+	if v == nil {
+		return nil
+	}
+	return &model.Visit{ID: v.ID, LocationID: v.LocationID, UserID: v.UserID, VisitedAt: v.VisitedAt, Mark: v.Mark}
+}
+
+func changedUserFields(a, b *model.User) []string {
+	var changed []string
+	if a.Email != b.Email {
+		changed = append(changed, "email")
+	}
+	if a.FirstName != b.FirstName {
+		changed = append(changed, "first_name")
+	}
+	if a.LastName != b.LastName {
+		changed = append(changed, "last_name")
+	}
+	if a.Gender != b.Gender {
+		changed = append(changed, "gender")
+	}
+	if a.BirthDate != b.BirthDate {
+		changed = append(changed, "birth_date")
+	}
+	return changed
+}
+
+func changedLocationFields(a, b *model.Location) []string {
+	var changed []string
+	if a.Place != b.Place {
+		changed = append(changed, "place")
+	}
+	if a.Country != b.Country {
+		changed = append(changed, "country")
+	}
+	if a.City != b.City {
+		changed = append(changed, "city")
+	}
+	if a.Distance != b.Distance {
+		changed = append(changed, "distance")
+	}
+	return changed
+}
+
+func changedVisitFields(a, b *model.Visit) []string {
+	var changed []string
+	if a.LocationID != b.LocationID {
+		changed = append(changed, "location")
+	}
+	if a.UserID != b.UserID {
+		changed = append(changed, "user")
+	}
+	if a.VisitedAt != b.VisitedAt {
+		changed = append(changed, "visited_at")
+	}
+	if a.Mark != b.Mark {
+		changed = append(changed, "mark")
+	}
+	return changed
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func TestStore_Events(t *testing.T) {
+	s := newTxTestStore()
+	s.EnableEvents(8)
+	s.SetCascadePolicy(CascadeDelete)
+	sub, err := s.Subscribe(s.LastEventSeq())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.UpdateUserByID(1, model.User{FirstName: opt.OString("x"), Gender: opt.OString("m")})
+	// updates that change nothing have no events
+	s.UpdateUserByID(1, model.User{FirstName: opt.OString("x")})
+	tx := s.Begin()
+	tx.UpdateLocationByID(1, model.Location{City: opt.OString(testLocation(1).City.V)})
+	tx.Commit()
+	// failed mutations have no events
+	s.UpdateUserByID(3, model.User{FirstName: opt.OString("x")})
+	tx = s.Begin()
+	tx.UpdateVisitByID(1, model.Visit{Mark: opt.OUint8(5)})
+	tx.UpdateUserByID(3, model.User{FirstName: opt.OString("x")})
+	tx.Commit()
+	s.AddVisit(model.Visit{ID: opt.OInt32(11), UserID: opt.OInt32(2), LocationID: opt.OInt32(2),
+		VisitedAt: opt.OInt64(1), Mark: opt.OUint8(4)})
+	if err := s.DeleteUser(2); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := sub.Next(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		typ    EventType
+		entity string
+		id     int32
+	}{
+		{EventUpdated, EntityUsers, 1},
+		{EventCreated, EntityVisits, 11},
+		{EventDeleted, EntityVisits, 11},
+		{EventDeleted, EntityUsers, 2},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i, e := range expected {
+		got := events[i]
+		if got.Seq != uint64(i+1) || got.Type != e.typ || got.Entity != e.entity || got.ID != e.id {
+			t.Errorf("expected %d %v %s %d, got %+v", i+1, e.typ, e.entity, e.id, got)
+		}
+	}
+	if before, after := events[0].Before.(*model.User), events[0].After.(*model.User); before.FirstName.V != "a" || after.FirstName.V != "x" {
+		t.Errorf("expected first_name a -> x, got %v -> %v", before, after)
+	}
+	if !reflect.DeepEqual(events[0].Changed, []string{"first_name"}) {
+		t.Errorf("expected first_name changed, got %v", events[0].Changed)
+	}
+	if events[1].Before != nil || events[3].After != nil {
+		t.Error("created event has before or deleted event has after")
+	}
+
+	if events, err := sub.Next(10 * time.Millisecond); err != nil || len(events) != 0 {
+		t.Errorf("expected timeout, got %v, %v", events, err)
+	}
+	go s.UpdateLocationByID(1, model.Location{City: opt.OString("x")})
+	if events, err := sub.Next(time.Second); err != nil || len(events) != 1 || events[0].Seq != 5 {
+		t.Errorf("expected the event 5, got %+v, %v", events, err)
+	}
+
+	for i := 0; i < 9; i++ {
+		s.UpdateLocationByID(1, model.Location{Distance: opt.OInt32(int32(i))})
+	}
+	if _, err := sub.Next(0); err != ErrEventsLost {
+		t.Errorf("expected ErrEventsLost for the slow subscriber, got %v", err)
+	}
+	if _, err := s.Subscribe(5); err != ErrEventsLost {
+		t.Errorf("expected ErrEventsLost, got %v", err)
+	}
+	if _, err := s.Subscribe(100); err != ErrEventsLost {
+		t.Errorf("expected ErrEventsLost for the future seq, got %v", err)
+	}
+	sub, err = s.Subscribe(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events, err := sub.Next(0); err != nil || len(events) != 8 || events[7].Seq != 14 {
+		t.Errorf("expected 8 replayed events, got %+v, %v", events, err)
+	}
+}
+
+func TestStore_ReplaceFromSnapshotSeq(t *testing.T) {
+	leader := newTxTestStore()
+	leader.EnableEvents(8)
+	leader.UpdateUserByID(1, model.User{FirstName: opt.OString("x")})
+	var b bytes.Buffer
+	seq, err := leader.WriteSnapshotSeq(&b)
+	if err != nil || seq != 1 {
+		t.Fatalf("expected snapshot at event 1, got %d, %v", seq, err)
+	}
+	leader.UpdateUserByID(1, model.User{FirstName: opt.OString("y")})
+
+	follower := NewStore()
+	follower.AddUser(testUser(5))
+	loaded := NewStore()
+	if err := loaded.ReadSnapshot(&b); err != nil {
+		t.Fatal(err)
+	}
+	follower.Replace(loaded)
+	if _, ok := follower.GetUserByID(5); ok {
+		t.Error("replaced user is kept")
+	}
+	if u, _ := follower.GetUserByID(1); u.FirstName.V != "x" {
+		t.Errorf("expected the user of the snapshot, got %v", u)
+	}
+	if visits, _ := follower.GetVisitsByUserID(1, nil, nil, nil, nil, nil); len(visits.Visits) != 10 {
+		t.Errorf("expected 10 visits, got %v", visits)
+	}
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson"
+)
+
+// Export passes a consistent copy of the store to f entity by entity:
+// users, locations and visits (EntityUsers, EntityLocations, EntityVisits), each of them in order of ids.
+// The entities are valid only during the call, the store is not changed until Export returns.
+// The first error of f stops the export and is returned.
+func (s *Store) Export(f func(entity string, v easyjson.Marshaler) error) error {
+	var locks lockSet
+	locks.rlockAll(visitLocks)
+	locks.rlockAll(locationLocks)
+	locks.rlockAll(userLocks)
+	s.acquire(&locks)
+	defer s.release(&locks)
+
+	var err error
+	s.usersByID.forEach(func(u *model.User) {
+		if err == nil {
+			err = f(EntityUsers, u)
+		}
+	})
+	s.locationsByID.forEach(func(l *model.Location) {
+		if err == nil {
+			err = f(EntityLocations, l)
+		}
+	})
+	s.visitsByID.forEach(func(v *model.Visit) {
+		if err == nil {
+			err = f(EntityVisits, v)
+		}
+	})
+	return err
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// Every kind of the store data is guarded by shardCount locks.
+// The shard of an id is chosen by its range, so one lock guards whole table pages
+// and mutations of unrelated users, locations and visits run in parallel.
+//
+// Locks are always taken in the order of the kinds and of the shards inside a kind,
+// readers included. The fields of a visit (links to its user and location too) are changed
+// only while holding the shards of the visit, of its location index and of its user index,
+// so holding any of them is enough to read the visit.
+// The fields of users and locations are guarded by their own shards,
+// readers that reach them through the visits take all the shards of the kind.
+const (
+	shardBits  = 4
+	shardCount = 1 << shardBits
+	shardMask  = shardCount - 1
+	allShards  = 1<<shardCount - 1
+)
+
+type lockKind int
+
+const (
+	visitLocks lockKind = iota
+	locationIndexLocks
+	userIndexLocks
+	locationLocks
+	userLocks
+	// locationSetLocks has a single shard guarding the country and city indexes
+	locationSetLocks
+	lockKinds
+)
+
+func shardOf(id int32) uint {
+	return uint(uint32(id)>>pageBits) & shardMask
+}
+
+// lockSet is a set of shards to be locked for reading or for writing
+type lockSet struct {
+	write [lockKinds]uint16
+	read  [lockKinds]uint16
+}
+
+// allLocks locks the whole store for writing
+var allLocks = lockSet{write: [lockKinds]uint16{allShards, allShards, allShards, allShards, allShards, 1}}
+
+func (l *lockSet) lock(kind lockKind, id int32) {
+	l.write[kind] |= 1 << shardOf(id)
+}
+
+func (l *lockSet) rlock(kind lockKind, id int32) {
+	l.read[kind] |= 1 << shardOf(id)
+}
+
+func (l *lockSet) rlockAll(kind lockKind) {
+	l.read[kind] = allShards
+}
+
+func (l *lockSet) add(other *lockSet) {
+	for k := range l.write {
+		l.write[k] |= other.write[k]
+		l.read[k] |= other.read[k]
+	}
+}
+
+func (s *Store) shard(kind lockKind, id int32) *sync.RWMutex {
+	return &s.locks[kind][shardOf(id)]
+}
+
+// acquire takes the shards of l in the global order, write lock wins if a shard is in both sets
+func (s *Store) acquire(l *lockSet) {
+	for k := range l.write {
+		for m := l.write[k] | l.read[k]; m != 0; m &= m - 1 {
+			i := bits.TrailingZeros16(m)
+			if l.write[k]&(1<<uint(i)) != 0 {
+				s.locks[k][i].Lock()
+			} else {
+				s.locks[k][i].RLock()
+			}
+		}
+	}
+}
+
+func (s *Store) release(l *lockSet) {
+	for k := range l.write {
+		for m := l.write[k] | l.read[k]; m != 0; m &= m - 1 {
+			i := bits.TrailingZeros16(m)
+			if l.write[k]&(1<<uint(i)) != 0 {
+				s.locks[k][i].Unlock()
+			} else {
+				s.locks[k][i].RUnlock()
+			}
+		}
+	}
+}
+
+// lockAll takes all the store locks for writing, mxCommit (if needed) is taken before them
+func (s *Store) lockAll() {
+	s.acquire(&allLocks)
+}
+
+func (s *Store) unlockAll() {
+	s.release(&allLocks)
+}
+
+// lockRecord takes the locks needed to apply rec and returns them for release.
+// The old user and location of a visit are known only under the visit lock,
+// it is the first kind in the order, so the rest are taken after it.
+func (s *Store) lockRecord(rec *walRecord) lockSet {
+	var l lockSet
+	switch rec.op {
+	case walAddUser:
+		id := rec.user.ID.V
+		l.lock(userIndexLocks, id)
+		l.lock(userLocks, id)
+		s.acquire(&l)
+		if vi := s.visitsByUserID.get(id); vi == nil || vi.Len() == 0 {
+			return l
+		}
+		// visits added before the user are linked to it, their locations are unknown
+		s.release(&l)
+	case walAddLocation:
+		id := rec.location.ID.V
+		l.lock(locationIndexLocks, id)
+		l.lock(locationLocks, id)
+		l.lock(locationSetLocks, 0)
+		s.acquire(&l)
+		if vl := s.visitsByLocationID.get(id); vl == nil || vl.Len() == 0 {
+			return l
+		}
+		s.release(&l)
+	case walUpdateUser:
+		l.lock(userLocks, rec.id)
+		s.acquire(&l)
+		return l
+	case walUpdateLocation:
+		l.lock(locationLocks, rec.id)
+		l.lock(locationSetLocks, 0)
+		s.acquire(&l)
+		return l
+	case walAddVisit:
+		v := rec.visit
+		l.lock(visitLocks, v.ID.V)
+		l.lock(locationIndexLocks, v.LocationID.V)
+		l.lock(userIndexLocks, v.UserID.V)
+		l.rlock(locationLocks, v.LocationID.V)
+		l.rlock(userLocks, v.UserID.V)
+		s.acquire(&l)
+		return l
+	case walUpdateVisit, walDeleteVisit:
+		l.lock(visitLocks, rec.id)
+		s.acquire(&l)
+		v := s.visitsByID.get(rec.id)
+		if v == nil {
+			// the mutation fails without changes
+			return l
+		}
+		var links lockSet
+		links.lock(locationIndexLocks, v.LocationID.V)
+		links.lock(userIndexLocks, v.UserID.V)
+		if rec.op == walUpdateVisit && rec.visit.LocationID.Defined {
+			links.lock(locationIndexLocks, rec.visit.LocationID.V)
+			links.rlock(locationLocks, rec.visit.LocationID.V)
+		}
+		if rec.op == walUpdateVisit && rec.visit.UserID.Defined {
+			links.lock(userIndexLocks, rec.visit.UserID.V)
+			links.rlock(userLocks, rec.visit.UserID.V)
+		}
+		s.acquire(&links)
+		l.add(&links)
+		return l
+	}
+	// deletes of users and locations and transactions touch visits anywhere
+	l = allLocks
+	s.acquire(&l)
+	return l
+}
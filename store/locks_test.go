@@ -0,0 +1,166 @@
+package store
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+// ids are spread over the shards
+const (
+	lockTestEntities = 64
+	lockTestVisits   = 2048
+	lockTestIDStep   = pageSize + 1
+)
+
+// testUser returns a valid user born at the epoch
+func testUser(id int32) model.User {
+	return model.User{ID: opt.OInt32(id), Email: opt.OString("a@b.c"), FirstName: opt.OString("a"),
+		LastName: opt.OString("b"), Gender: opt.OString("m"), BirthDate: opt.OInt64(0)}
+}
+
+// testLocation returns a valid location of country "c"
+func testLocation(id int32) model.Location {
+	return model.Location{ID: opt.OInt32(id), Place: opt.OString("p"), Country: opt.OString("c"),
+		City: opt.OString("c"), Distance: opt.OInt32(1)}
+}
+
+// addTestEntities adds a user and a location of every id
+func addTestEntities(s *Store, ids ...int32) {
+	for _, id := range ids {
+		s.AddUser(testUser(id))
+		s.AddLocation(testLocation(id))
+	}
+}
+
+func newLockTestStore() *Store {
+	s := NewStore()
+	for i := int32(0); i < lockTestEntities; i++ {
+		addTestEntities(s, i*lockTestIDStep)
+	}
+	for i := int32(0); i < lockTestVisits; i++ {
+		entity := i % lockTestEntities * lockTestIDStep
+		s.AddVisit(model.Visit{ID: opt.OInt32(i * 7), UserID: opt.OInt32(entity), LocationID: opt.OInt32(entity),
+			VisitedAt: opt.OInt64(int64(i)), Mark: opt.OUint8(uint8(i % 6))})
+	}
+	return s
+}
+
+// writeMix makes a random mutation, visits are moved between users and locations
+func writeMix(s *Store, r *rand.Rand, tx bool) {
+	entity := int32(r.Intn(lockTestEntities)) * lockTestIDStep
+	var rec *walRecord
+	switch r.Intn(4) {
+	case 0:
+		rec = &walRecord{op: walUpdateUser, id: entity, user: &model.User{BirthDate: opt.OInt64(r.Int63n(1e8))}}
+	case 1:
+		rec = &walRecord{op: walUpdateLocation, id: entity, location: &model.Location{Distance: opt.OInt32(r.Int31n(100))}}
+	default:
+		rec = &walRecord{op: walUpdateVisit, id: int32(r.Intn(lockTestVisits)) * 7, visit: &model.Visit{
+			UserID: opt.OInt32(entity), LocationID: opt.OInt32(entity), VisitedAt: opt.OInt64(r.Int63n(1e6)), Mark: opt.OUint8(uint8(r.Intn(6)))}}
+	}
+	if tx {
+		// transactions take all the locks like the store did before sharding
+		rec = &walRecord{op: walTx, tx: []*walRecord{rec}}
+	}
+	s.commit(rec)
+}
+
+func TestStore_ShardedWrites(t *testing.T) {
+	s := newLockTestStore()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 2000; i++ {
+				switch {
+				case i%2 == 0:
+					writeMix(s, r, i%10 == 1)
+				case i%4 == 1:
+					s.GetVisitsByUserID(int32(r.Intn(lockTestEntities))*lockTestIDStep, nil, nil, nil, nil, nil)
+				default:
+					gender := "m"
+					s.GetLocationAvg(int32(r.Intn(lockTestEntities))*lockTestIDStep, nil, nil, nil, nil, &gender)
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+
+	byUser := make(map[int32]int)
+	byLocation := make(map[int32]int)
+	for i := int32(0); i < lockTestVisits; i++ {
+		visit, _ := s.GetVisitByID(i * 7)
+		byUser[visit.UserID.V]++
+		byLocation[visit.LocationID.V]++
+	}
+	for i := int32(0); i < lockTestEntities; i++ {
+		id := i * lockTestIDStep
+		visits, _ := s.GetVisitsByUserID(id, nil, nil, nil, nil, nil)
+		if len(visits.Visits) != byUser[id] {
+			t.Errorf("expected %d visits of user %d, got %d", byUser[id], id, len(visits.Visits))
+		}
+		stats, _ := s.GetLocationStats(id, nil, nil, nil, nil, nil)
+		if stats.Count != byLocation[id] {
+			t.Errorf("expected %d visits of location %d, got %d", byLocation[id], id, stats.Count)
+		}
+	}
+}
+
+// BenchmarkStore_ReadsUnderWrites measures the latency of the reads while 4 writers
+// update users, locations and visits every 20µs each.
+// "global" writers take all the locks (like a single store mutex), "sharded" ones take only the needed shards.
+func BenchmarkStore_ReadsUnderWrites(b *testing.B) {
+	for _, c := range []struct {
+		name   string
+		global bool
+	}{{"sharded", false}, {"global", true}} {
+		b.Run(c.name, func(b *testing.B) {
+			s := newLockTestStore()
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			for g := 0; g < 4; g++ {
+				wg.Add(1)
+				go func(seed int64) {
+					defer wg.Done()
+					r := rand.New(rand.NewSource(seed))
+					ticker := time.NewTicker(20 * time.Microsecond)
+					defer ticker.Stop()
+					for {
+						select {
+						case <-stop:
+							return
+						case <-ticker.C:
+							writeMix(s, r, c.global)
+						}
+					}
+				}(int64(g))
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewSource(rand.Int63()))
+				for i := 0; pb.Next(); i++ {
+					id := int32(r.Intn(lockTestEntities)) * lockTestIDStep
+					switch i % 3 {
+					case 0:
+						s.GetUserByID(id)
+					case 1:
+						s.GetVisitByID(int32(r.Intn(lockTestVisits)) * 7)
+					default:
+						s.GetLocationAvg(id, nil, nil, nil, nil, nil)
+					}
+				}
+			})
+			b.StopTimer()
+			close(stop)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package store
+
+import "github.com/la0rg/highloadcup/model"
+
+// markTree is a treap ordered by (visited_at, id) like VisitIndex.
+// Every node keeps the sum and the number of the marks in its subtree,
+// so the average mark of any date range is found in O(log n).
+type markTree struct {
+	root *markNode
+	seed uint32
+}
+
+type markNode struct {
+	visitedAt   int64
+	id          int32
+	mark        uint8
+	priority    uint32
+	left, right *markNode
+	sum         int64
+	count       int
+}
+
+func newMarkTree() *markTree {
+	return &markTree{seed: 2463534242}
+}
+
+func (n *markNode) less(visitedAt int64, id int32) bool {
+	if n.visitedAt != visitedAt {
+		return n.visitedAt < visitedAt
+	}
+	return n.id < id
+}
+
+func (n *markNode) update() {
+	n.sum = int64(n.mark)
+	n.count = 1
+	if n.left != nil {
+		n.sum += n.left.sum
+		n.count += n.left.count
+	}
+	if n.right != nil {
+		n.sum += n.right.sum
+		n.count += n.right.count
+	}
+}
+
+// split divides the tree into nodes less than the key and the rest
+func split(n *markNode, visitedAt int64, id int32) (*markNode, *markNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if n.less(visitedAt, id) {
+		l, r := split(n.right, visitedAt, id)
+		n.right = l
+		n.update()
+		return n, r
+	}
+	l, r := split(n.left, visitedAt, id)
+	n.left = r
+	n.update()
+	return l, n
+}
+
+// merge joins two trees, all the keys of l should be less than the keys of r
+func merge(l, r *markNode) *markNode {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+	if l.priority > r.priority {
+		l.right = merge(l.right, r)
+		l.update()
+		return l
+	}
+	r.left = merge(l, r.left)
+	r.update()
+	return r
+}
+
+func (t *markTree) random() uint32 {
+	// xorshift32
+	t.seed ^= t.seed << 13
+	t.seed ^= t.seed >> 17
+	t.seed ^= t.seed << 5
+	return t.seed
+}
+
+func (t *markTree) insert(visit *model.Visit) {
+	n := &markNode{
+		visitedAt: visit.VisitedAt.V,
+		id:        visit.ID.V,
+		mark:      visit.Mark.V,
+		priority:  t.random(),
+	}
+	n.update()
+	l, r := split(t.root, n.visitedAt, n.id)
+	t.root = merge(merge(l, n), r)
+}
+
+func (t *markTree) remove(visit *model.Visit) {
+	l, r := split(t.root, visit.VisitedAt.V, visit.ID.V)
+	// r starts with the visit if it is in the tree
+	if r != nil {
+		first := r
+		for first.left != nil {
+			first = first.left
+		}
+		if first.visitedAt == visit.VisitedAt.V && first.id == visit.ID.V {
+			r = removeFirst(r)
+		}
+	}
+	t.root = merge(l, r)
+}
+
+func removeFirst(n *markNode) *markNode {
+	if n.left == nil {
+		return n.right
+	}
+	n.left = removeFirst(n.left)
+	n.update()
+	return n
+}
+
+// less returns the sum and the number of the marks with key less than (visitedAt, id)
+func (t *markTree) less(visitedAt int64, id int32) (int64, int) {
+	var sum int64
+	var count int
+	for n := t.root; n != nil; {
+		if n.less(visitedAt, id) {
+			sum += int64(n.mark)
+			count++
+			if n.left != nil {
+				sum += n.left.sum
+				count += n.left.count
+			}
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return sum, count
+}
+
+// total returns the sum and the number of all the marks
+func (t *markTree) total() (int64, int) {
+	if t.root == nil {
+		return 0, 0
+	}
+	return t.root.sum, t.root.count
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func TestVisitIndex_MarkSum(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	vi := NewLocationVisitIndex()
+	visits := make(map[int32]*model.Visit)
+	for i := 0; i < 2000; i++ {
+		id := int32(r.Intn(300))
+		if v, ok := visits[id]; ok && r.Intn(2) == 0 {
+			vi.Remove(v)
+			delete(visits, id)
+			continue
+		}
+		v := &model.Visit{ID: opt.OInt32(id), VisitedAt: opt.OInt64(int64(r.Intn(50))), Mark: opt.OUint8(uint8(r.Intn(6)))}
+		if old, ok := visits[id]; ok {
+			vi.Remove(old)
+		}
+		vi.Add(v)
+		visits[id] = v
+
+		fromDate, toDate := int64(r.Intn(50)), int64(r.Intn(50))
+		var expectedSum int64
+		var expectedCount int
+		for _, v := range visits {
+			if fromDate < v.VisitedAt.V && v.VisitedAt.V < toDate {
+				expectedSum += int64(v.Mark.V)
+				expectedCount++
+			}
+		}
+		sum, count := vi.MarkSum(&fromDate, &toDate)
+		if sum != expectedSum || count != expectedCount {
+			t.Fatalf("step %d (%d, %d): expected %d/%d, got %d/%d", i, fromDate, toDate, expectedSum, expectedCount, sum, count)
+		}
+		if _, count := vi.MarkSum(nil, nil); count != len(visits) {
+			t.Fatalf("step %d: expected %d visits, got %d", i, len(visits), count)
+		}
+	}
+}
+
+func TestStore_LocationAvgAfterUpdates(t *testing.T) {
+	s := NewStore()
+	addTestEntities(s, 1, 2, 3)
+	r := rand.New(rand.NewSource(1))
+	for i := int32(1); i <= 100; i++ {
+		s.AddVisit(model.Visit{ID: opt.OInt32(i), UserID: opt.OInt32(1 + i%3), LocationID: opt.OInt32(1 + i%3),
+			VisitedAt: opt.OInt64(int64(r.Intn(20))), Mark: opt.OUint8(uint8(r.Intn(6)))})
+	}
+	for i := 0; i < 300; i++ {
+		var update model.Visit
+		switch r.Intn(4) {
+		case 0:
+			update.LocationID = opt.OInt32(int32(1 + r.Intn(3)))
+		case 1:
+			update.Mark = opt.OUint8(uint8(r.Intn(6)))
+		case 2:
+			update.VisitedAt = opt.OInt64(int64(r.Intn(20)))
+		case 3:
+			update.UserID = opt.OInt32(int32(1 + r.Intn(3)))
+		}
+		s.UpdateVisitByID(int32(1+r.Intn(100)), update)
+
+		// gender filter makes the store scan the visits, all the users are "m"
+		gender := "m"
+		fromDate, toDate := int64(r.Intn(20)), int64(r.Intn(20))
+		for id := int32(1); id <= 3; id++ {
+			fast, _ := s.GetLocationAvg(id, &fromDate, &toDate, nil, nil, nil)
+			scan, _ := s.GetLocationAvg(id, &fromDate, &toDate, nil, nil, &gender)
+			if fast != scan {
+				t.Fatalf("step %d, location %d: aggregated avg %v, scanned avg %v", i, id, fast, scan)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"math"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+// Page selects a part of the visits ordered by (visited_at, id)
+type Page struct {
+	// Limit is the maximum number of the visits, 0 means no limit
+	Limit int
+	// Offset is the number of the matching visits to skip
+	Offset int
+	// After is the position to continue from (exclusive), usually the Next cursor of the previous page
+	After *model.Cursor
+	// Desc reverses the order
+	Desc bool
+}
+
+// pager counts visits accepted by a filter and stops the iteration when the page is full
+type pager struct {
+	page *Page
+	n    int
+	last *model.Visit
+	more bool
+}
+
+// accept returns false if the visit should be skipped,
+// stop is true when the iteration should be finished
+func (p *pager) accept(visit *model.Visit) (ok bool, stop bool) {
+	if p.page == nil {
+		return true, false
+	}
+	if p.page.Offset > p.n {
+		p.n++
+		return false, false
+	}
+	if p.page.Limit > 0 && p.n-p.page.Offset >= p.page.Limit {
+		p.more = true
+		return false, true
+	}
+	p.n++
+	p.last = visit
+	return true, false
+}
+
+// next is a cursor for the following page (nil if it is the last one)
+func (p *pager) next() *model.Cursor {
+	if !p.more || p.last == nil {
+		return nil
+	}
+	return &model.Cursor{VisitedAt: p.last.VisitedAt.V, ID: p.last.ID.V}
+}
+
+func visitKey(visitedAt int64, id int32) VisitItem {
+	return VisitItem{&model.Visit{
+		ID:        opt.Int32{V: id, Defined: true},
+		VisitedAt: opt.Int64{V: visitedAt, Defined: true},
+	}}
+}
+
+// afterCursor is the first possible key following the cursor
+func afterCursor(c *model.Cursor) VisitItem {
+	if c.ID == math.MaxInt32 {
+		return visitKey(c.VisitedAt+1, math.MinInt32)
+	}
+	return visitKey(c.VisitedAt, c.ID+1)
+}
+
+// beforeCursor is the last possible key preceding the cursor
+func beforeCursor(c *model.Cursor) VisitItem {
+	if c.ID == math.MinInt32 {
+		return visitKey(c.VisitedAt-1, math.MaxInt32)
+	}
+	return visitKey(c.VisitedAt, c.ID-1)
+}
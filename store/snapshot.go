@@ -0,0 +1,173 @@
+package store
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/la0rg/highloadcup/model"
+)
+
+// Snapshot layout (all integers are varints): magic "HLCS", version, created at (unix seconds),
+// users count, users, locations count, locations, visits count, visits
+// and crc32 of everything above (little endian).
+// VisitIndex trees are not stored: they are rebuilt while the visits are added back.
+var snapshotMagic = []byte("HLCS")
+
+const snapshotVersion = 1
+
+var ErrSnapshotFormat = errors.New("Unsupported snapshot format")
+
+// WriteSnapshot writes a consistent binary copy of the store into w
+func (s *Store) WriteSnapshot(w io.Writer) error {
+	_, err := s.WriteSnapshotSeq(w)
+	return err
+}
+
+// WriteSnapshotSeq writes the snapshot like WriteSnapshot and returns the sequence number
+// of the last event included into it (see EnableEvents), the later events are not in the snapshot
+func (s *Store) WriteSnapshotSeq(w io.Writer) (uint64, error) {
+	// every mutation holds a visit, location or user shard for writing
+	// until its events are published, so none of them is in progress
+	var locks lockSet
+	locks.rlockAll(visitLocks)
+	locks.rlockAll(locationLocks)
+	locks.rlockAll(userLocks)
+	s.acquire(&locks)
+	defer s.release(&locks)
+	return s.LastEventSeq(), s.writeSnapshot(w)
+}
+
+// writeSnapshot encodes the entities, the caller holds the visit, location and user shards
+func (s *Store) writeSnapshot(w io.Writer) error {
+	e := newEncoder(w)
+	e.write(snapshotMagic)
+	e.byte(snapshotVersion)
+	e.varint(time.Now().Unix())
+
+	e.uvarint(uint64(s.usersByID.len()))
+	s.usersByID.forEach(func(u *model.User) {
+		e.user(u)
+	})
+	e.uvarint(uint64(s.locationsByID.len()))
+	s.locationsByID.forEach(func(l *model.Location) {
+		e.location(l)
+	})
+	e.uvarint(uint64(s.visitsByID.len()))
+	s.visitsByID.forEach(func(v *model.Visit) {
+		e.visit(v)
+	})
+	e.checksum()
+	return e.flush()
+}
+
+// ReadSnapshot loads entities written by WriteSnapshot into the (empty) store
+func (s *Store) ReadSnapshot(r io.Reader) error {
+	d := newDecoder(r)
+	magic := make([]byte, len(snapshotMagic))
+	d.read(magic)
+	if d.err != nil {
+		return d.err
+	}
+	if string(magic) != string(snapshotMagic) || d.byte() != snapshotVersion {
+		return ErrSnapshotFormat
+	}
+	d.varint() // created at
+
+	// entities are validated by the checksum only after the whole file is read,
+	// so keep them aside until then
+	var users []model.User
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		var u model.User
+		d.user(&u)
+		users = append(users, u)
+	}
+	var locations []model.Location
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		var l model.Location
+		d.location(&l)
+		locations = append(locations, l)
+	}
+	var visits []model.Visit
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		var v model.Visit
+		d.visit(&v)
+		visits = append(visits, v)
+	}
+	d.checksum()
+	if d.err != nil {
+		return d.err
+	}
+
+	for i := range users {
+		if err := s.AddUser(users[i]); err != nil {
+			return err
+		}
+	}
+	for i := range locations {
+		if err := s.AddLocation(locations[i]); err != nil {
+			return err
+		}
+	}
+	for i := range visits {
+		if err := s.AddVisit(visits[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot atomically replaces the file at path with a new snapshot.
+// The WAL (if any) is truncated afterwards as all its records are in the snapshot.
+func (s *Store) SaveSnapshot(path string) error {
+	if s.wal != nil {
+		s.mxCommit.Lock()
+		defer s.mxCommit.Unlock()
+	}
+	tmp, err := os.Create(filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp"))
+	if err != nil {
+		return err
+	}
+	err = s.WriteSnapshot(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil || s.wal == nil {
+		return err
+	}
+	return s.wal.truncate()
+}
+
+// Replace moves the entities of other into the store, other should not be used afterwards.
+// Subscribers get no events about the replaced entities.
+func (s *Store) Replace(other *Store) {
+	s.lockAll()
+	defer s.unlockAll()
+	s.visitsByUserID, other.visitsByUserID = other.visitsByUserID, nil
+	s.usersByID, other.usersByID = other.usersByID, nil
+	s.visitsByID, other.visitsByID = other.visitsByID, nil
+	s.visitsByLocationID, other.visitsByLocationID = other.visitsByLocationID, nil
+	s.locationsByID, other.locationsByID = other.locationsByID, nil
+	s.locationsByCountry, other.locationsByCountry = other.locationsByCountry, nil
+	s.locationsByCity, other.locationsByCity = other.locationsByCity, nil
+}
+
+// LoadSnapshot reads the snapshot file at path into the store
+func (s *Store) LoadSnapshot(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.ReadSnapshot(f)
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func TestStore_SnapshotRoundTrip(t *testing.T) {
+	s := newTxTestStore()
+	s.UpdateUserByID(2, model.User{FirstName: opt.OString("x")})
+	s.UpdateVisitByID(3, model.Visit{LocationID: opt.OInt32(2), Mark: opt.OUint8(5)})
+	s.DeleteVisit(4)
+	var b bytes.Buffer
+	if err := s.WriteSnapshot(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewStore()
+	if err := loaded.ReadSnapshot(bytes.NewReader(b.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	for id := int32(1); id <= 2; id++ {
+		u, _ := s.GetUserByID(id)
+		if got, ok := loaded.GetUserByID(id); !ok || !reflect.DeepEqual(got, u) {
+			t.Errorf("expected user %+v, got %+v", u, got)
+		}
+		l, _ := s.GetLocationByID(id)
+		if got, ok := loaded.GetLocationByID(id); !ok || !reflect.DeepEqual(got, l) {
+			t.Errorf("expected location %+v, got %+v", l, got)
+		}
+		avg, _ := s.GetLocationAvg(id, nil, nil, nil, nil, nil)
+		if got, _ := loaded.GetLocationAvg(id, nil, nil, nil, nil, nil); got != avg {
+			t.Errorf("location %d: expected avg %v, got %v", id, avg, got)
+		}
+	}
+	for id := int32(1); id <= 10; id++ {
+		v, ok := s.GetVisitByID(id)
+		if got, gotOK := loaded.GetVisitByID(id); gotOK != ok || ok && !reflect.DeepEqual(got, v) {
+			t.Errorf("expected visit %+v (%v), got %+v (%v)", v, ok, got, gotOK)
+		}
+	}
+	if visits, _ := loaded.GetVisitsByUserID(1, nil, nil, nil, nil, nil); len(visits.Visits) != 9 {
+		t.Errorf("expected 9 visits of user 1, got %d", len(visits.Visits))
+	}
+
+	// the email of a user is changed, only the checksum tells it
+	corrupted := bytes.Replace(b.Bytes(), []byte("a@b.c"), []byte("a@b.d"), 1)
+	loaded = NewStore()
+	if err := loaded.ReadSnapshot(bytes.NewReader(corrupted)); err != ErrCorrupted {
+		t.Errorf("expected ErrCorrupted, got %v", err)
+	}
+	if _, ok := loaded.GetUserByID(1); ok {
+		t.Error("entities of the corrupted snapshot are loaded")
+	}
+	if err := NewStore().ReadSnapshot(bytes.NewReader(b.Bytes()[:b.Len()-1])); err == nil {
+		t.Error("truncated snapshot is loaded")
+	}
+	if err := NewStore().ReadSnapshot(bytes.NewReader([]byte("HLCW\x01"))); err != ErrSnapshotFormat {
+		t.Errorf("expected ErrSnapshotFormat, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"math"
+
+	"github.com/la0rg/highloadcup/model"
+)
+
+// GetLocationStats returns the distribution of the marks of the location visits.
+// Filters are the same as in GetLocationAvg.
+func (s *Store) GetLocationStats(id int32, fromDate *int64, toDate *int64, fromAge *int64, toAge *int64, gender *string) (*model.Stats, bool) {
+	locks := locationVisitsLocks(id, fromAge, toAge, gender)
+	s.acquire(&locks)
+	defer s.release(&locks)
+	if !s.locationExists(id) {
+		return nil, false
+	}
+	vi := s.visitsByLocationID.get(id)
+	if vi == nil {
+		return nil, false
+	}
+	visits := vi.GetByAgeAndGender(fromDate, toDate, fromAge, toAge, gender)
+	return statsOf(visits), true
+}
+
+func statsOf(visits []model.Visit) *model.Stats {
+	stats := &model.Stats{Count: len(visits)}
+	if len(visits) == 0 {
+		return stats
+	}
+	visitors := make(map[int32]struct{})
+	var sum float64
+	for i := range visits {
+		mark := visits[i].Mark.V
+		if int(mark) < len(stats.Histogram) {
+			stats.Histogram[mark]++
+		}
+		sum += float64(mark)
+		visitors[visits[i].UserID.V] = struct{}{}
+	}
+	stats.Visitors = len(visitors)
+	stats.Avg = sum / float64(len(visits))
+	for i := range visits {
+		d := float64(visits[i].Mark.V) - stats.Avg
+		stats.StdDev += d * d
+	}
+	stats.StdDev = math.Sqrt(stats.StdDev / float64(len(visits)))
+	// the middle marks are found in the histogram
+	lo, hi := nth(stats.Histogram, (len(visits)-1)/2), nth(stats.Histogram, len(visits)/2)
+	stats.Median = float64(lo+hi) / 2
+	return stats
+}
+
+// nth returns the n-th (from 0) mark in the sorted order
+func nth(histogram [6]int, n int) int {
+	for mark, count := range histogram {
+		if n < count {
+			return mark
+		}
+		n -= count
+	}
+	return len(histogram) - 1
+}
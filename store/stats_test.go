@@ -0,0 +1,66 @@
+package store
+
+import (
+	"math"
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func TestStore_LocationStats(t *testing.T) {
+	s := NewStore()
+	addTestEntities(s, 1, 2, 3)
+	s.UpdateUserByID(2, model.User{Gender: opt.OString("f")})
+	s.UpdateUserByID(3, model.User{BirthDate: opt.OInt64(1e8)})
+	visits := []struct {
+		user      int32
+		visitedAt int64
+		mark      uint8
+	}{
+		{1, 10, 1}, {1, 20, 3}, {2, 30, 5}, {2, 40, 5}, {3, 50, 0},
+	}
+	for i, v := range visits {
+		s.AddVisit(model.Visit{ID: opt.OInt32(int32(i + 1)), UserID: opt.OInt32(v.user), LocationID: opt.OInt32(1),
+			VisitedAt: opt.OInt64(v.visitedAt), Mark: opt.OUint8(v.mark)})
+	}
+
+	date, age, male, female, unknown := int64(15), int64(5e7), "m", "f", "x"
+	cases := []struct {
+		name     string
+		fromDate *int64
+		fromAge  *int64
+		gender   *string
+		expected model.Stats
+	}{
+		{"all, odd count", nil, nil, nil,
+			model.Stats{Count: 5, Visitors: 3, Histogram: [6]int{1, 1, 0, 1, 0, 2}, Avg: 2.8, Median: 3, StdDev: math.Sqrt(20.8 / 5)}},
+		{"date, even count", &date, nil, nil,
+			model.Stats{Count: 4, Visitors: 3, Histogram: [6]int{1, 0, 0, 1, 0, 2}, Avg: 3.25, Median: 4, StdDev: math.Sqrt(16.75 / 4)}},
+		{"age", nil, &age, nil,
+			model.Stats{Count: 4, Visitors: 2, Histogram: [6]int{0, 1, 0, 1, 0, 2}, Avg: 3.5, Median: 4, StdDev: math.Sqrt(11.0 / 4)}},
+		{"male", nil, nil, &male,
+			model.Stats{Count: 3, Visitors: 2, Histogram: [6]int{1, 1, 0, 1, 0, 0}, Avg: 4.0 / 3, Median: 1, StdDev: math.Sqrt(42.0 / 27)}},
+		{"female, same marks", nil, nil, &female,
+			model.Stats{Count: 2, Visitors: 1, Histogram: [6]int{0, 0, 0, 0, 0, 2}, Avg: 5, Median: 5}},
+		{"no visits", nil, nil, &unknown, model.Stats{}},
+	}
+	for _, c := range cases {
+		stats, ok := s.GetLocationStats(1, c.fromDate, nil, c.fromAge, nil, c.gender)
+		if !ok {
+			t.Fatalf("%s: location is not found", c.name)
+		}
+		e := c.expected
+		if stats.Count != e.Count || stats.Visitors != e.Visitors || stats.Histogram != e.Histogram ||
+			math.Abs(stats.Avg-e.Avg) > 1e-9 || stats.Median != e.Median || math.Abs(stats.StdDev-e.StdDev) > 1e-9 {
+			t.Errorf("%s: expected %+v, got %+v", c.name, e, *stats)
+		}
+		avg, _ := s.GetLocationAvg(1, c.fromDate, nil, c.fromAge, nil, c.gender)
+		if math.Abs(avg-stats.Avg) > 1e-6 {
+			t.Errorf("%s: avg %v of the stats differs from %v", c.name, stats.Avg, avg)
+		}
+	}
+	if _, ok := s.GetLocationStats(4, nil, nil, nil, nil, nil); ok {
+		t.Error("stats of a missing location")
+	}
+}
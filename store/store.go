@@ -8,61 +8,85 @@ import (
 )
 
 var (
-	ErrRequiredFields = errors.New("Not all required fields are filled")
+	ErrRequiredFields = errors.New("Required field is not filled")
 	ErrAlreadyExist   = errors.New("Already exist")
 	ErrDoesNotExist   = errors.New("Does not exist")
 	ErrIDInUpdate     = errors.New("Update should not contain ID in the json object")
+	ErrInvalidID      = errors.New("ID should not be negative")
 )
 
 // Store is an object that keeps all the data (in memory)
 // and provides all the aggregation functions
 type Store struct {
-	mxUsersByID          sync.RWMutex
-	mxVisitsByID         sync.RWMutex
-	mxVisitsByUserID     sync.RWMutex
-	mxVisitsByLocationID sync.RWMutex
-	mxLocationsByID      sync.RWMutex
-	visitsByUserID       [1100000]*VisitIndex
-	usersByID            [1100000]*model.User
-	visitsByID           [11000000]*model.Visit
-	visitsByLocationID   [1000000]*VisitIndex
-	locationsByID        [1000000]*model.Location
+	locks              [lockKinds][shardCount]sync.RWMutex
+	mxCommit           sync.RWMutex
+	wal                *WAL
+	feed               *feed
+	cascade            CascadePolicy
+	visitsByUserID     *indexTable
+	usersByID          *userTable
+	visitsByID         *visitTable
+	visitsByLocationID *indexTable
+	locationsByID      *locationTable
+	locationsByCountry map[string]map[int32]struct{}
+	locationsByCity    map[string]map[int32]struct{}
 }
 
 // NewStore constructor
 func NewStore() *Store {
-	return &Store{}
+	return &Store{
+		visitsByUserID:     &indexTable{},
+		usersByID:          &userTable{},
+		visitsByID:         &visitTable{},
+		visitsByLocationID: &indexTable{},
+		locationsByID:      &locationTable{},
+	}
 }
 
 // AddUser adds new user to the store
 func (s *Store) AddUser(user model.User) error {
-	if !user.BirthDate.Defined || !user.Email.Defined || !user.FirstName.Defined ||
-		!user.LastName.Defined || !user.Gender.Defined || !user.ID.Defined {
-		return ErrRequiredFields
-	}
-	s.mxUsersByID.Lock()
-	if user.ID.V >= int32(len(s.usersByID)) || user.ID.V < 0 {
-		s.mxUsersByID.Unlock()
-		return ErrDoesNotExist
-	}
-	if s.usersByID[user.ID.V] != nil {
-		s.mxUsersByID.Unlock()
-		return ErrAlreadyExist
+	return s.commit(&walRecord{op: walAddUser, user: &user})
+}
+
+func (s *Store) addUser(user model.User) error {
+	if err := s.checkAddUser(&user); err != nil {
+		return err
 	}
-	s.usersByID[user.ID.V] = &user
-	s.mxUsersByID.Unlock()
+	s.usersByID.set(user.ID.V, &user)
 
-	s.mxVisitsByUserID.Lock()
 	// initialize visitsByUserID with empty index (to return [] if user exist and visits were not added)
-	vi := s.visitsByUserID[user.ID.V]
+	vi := s.visitsByUserID.get(user.ID.V)
 	if vi != nil {
 		vi.ApplyToAll(func(visit *model.Visit) {
 			visit.User = &user
 		})
 	} else {
-		s.visitsByUserID[user.ID.V] = NewVisitIndex()
+		s.visitsByUserID.set(user.ID.V, NewVisitIndex())
+	}
+	return nil
+}
+
+// checkAddUser returns the error addUser fails with
+func (s *Store) checkAddUser(user *model.User) error {
+	var required requiredFields
+	required.check(user.ID.Defined, "id")
+	required.check(user.Email.Defined, "email")
+	required.check(user.FirstName.Defined, "first_name")
+	required.check(user.LastName.Defined, "last_name")
+	required.check(user.Gender.Defined, "gender")
+	required.check(user.BirthDate.Defined, "birth_date")
+	if err := required.err(); err != nil {
+		return err
+	}
+	if err := model.ValidateUser(user); err != nil {
+		return err
+	}
+	if user.ID.V < 0 {
+		return ErrInvalidID
+	}
+	if s.usersByID.get(user.ID.V) != nil {
+		return ErrAlreadyExist
 	}
-	s.mxVisitsByUserID.Unlock()
 	return nil
 }
 
@@ -70,33 +94,33 @@ func (s *Store) AddUser(user model.User) error {
 // returns user and existence flag (map like)
 func (s *Store) GetUserByID(id int32) (*model.User, bool) {
 	var result model.User
-	s.mxUsersByID.RLock()
-	defer s.mxUsersByID.RUnlock()
-	if id >= int32(len(s.usersByID)) || id < 0 {
-		return nil, false
-	}
-	u := s.usersByID[id]
+	mx := s.shard(userLocks, id)
+	mx.RLock()
+	defer mx.RUnlock()
+	u := s.usersByID.get(id)
 	if u != nil {
 		result = *u // return copy of the object pointed by u
 	}
 	return &result, u != nil
 }
 
+// userExists checks id without copying the user (visits of deleted users are kept by CascadeOrphan),
+// the caller holds the user shard
+func (s *Store) userExists(id int32) bool {
+	return s.usersByID.get(id) != nil
+}
+
 // UpdateUserByID updates user with id by user
 // if user does not exist returns error
 func (s *Store) UpdateUserByID(id int32, user model.User) error {
-	s.mxUsersByID.Lock()
-	defer s.mxUsersByID.Unlock()
-	if id >= int32(len(s.usersByID)) || id < 0 {
-		return ErrDoesNotExist
-	}
-	u := s.usersByID[id]
-	if u == nil {
-		return ErrDoesNotExist
-	}
-	if user.ID.Defined {
-		return ErrIDInUpdate
+	return s.commit(&walRecord{op: walUpdateUser, id: id, user: &user})
+}
+
+func (s *Store) updateUserByID(id int32, user model.User) error {
+	if err := s.checkUpdateUser(id, &user); err != nil {
+		return err
 	}
+	u := s.usersByID.get(id)
 	if user.BirthDate.Defined {
 		u.BirthDate = user.BirthDate
 	}
@@ -115,48 +139,72 @@ func (s *Store) UpdateUserByID(id int32, user model.User) error {
 	return nil
 }
 
-// AddLocation adds new location to the store
-func (s *Store) AddLocation(location model.Location) error {
-	if !location.City.Defined || !location.Country.Defined || !location.Distance.Defined ||
-		!location.ID.Defined || !location.Place.Defined {
-		return ErrRequiredFields
-	}
-	s.mxLocationsByID.Lock()
-	if location.ID.V >= int32(len(s.locationsByID)) || location.ID.V < 0 {
-		s.mxLocationsByID.Unlock()
+// checkUpdateUser returns the error updateUserByID fails with
+func (s *Store) checkUpdateUser(id int32, user *model.User) error {
+	if s.usersByID.get(id) == nil {
 		return ErrDoesNotExist
 	}
-	if s.locationsByID[location.ID.V] != nil {
-		s.mxLocationsByID.Unlock()
-		return ErrAlreadyExist
+	if user.ID.Defined {
+		return ErrIDInUpdate
 	}
-	s.locationsByID[location.ID.V] = &location
-	s.mxLocationsByID.Unlock()
+	return model.ValidateUser(user)
+}
+
+// AddLocation adds new location to the store
+func (s *Store) AddLocation(location model.Location) error {
+	return s.commit(&walRecord{op: walAddLocation, location: &location})
+}
+
+func (s *Store) addLocation(location model.Location) error {
+	if err := s.checkAddLocation(&location); err != nil {
+		return err
+	}
+	s.locationsByID.set(location.ID.V, &location)
+	s.indexLocation(&location)
 
-	s.mxVisitsByLocationID.Lock()
 	// update connections (if already exist to this entity)
-	vi := s.visitsByLocationID[location.ID.V]
+	vi := s.visitsByLocationID.get(location.ID.V)
 	if vi != nil {
 		vi.ApplyToAll(func(visit *model.Visit) {
 			visit.Location = &location
 		})
 	} else {
 		// initialize visitsByLocationID with empty index (to return 0 avg)
-		s.visitsByLocationID[location.ID.V] = NewVisitIndex()
+		s.visitsByLocationID.set(location.ID.V, NewLocationVisitIndex())
+	}
+	return nil
+}
+
+// checkAddLocation returns the error addLocation fails with
+func (s *Store) checkAddLocation(location *model.Location) error {
+	var required requiredFields
+	required.check(location.ID.Defined, "id")
+	required.check(location.Place.Defined, "place")
+	required.check(location.Country.Defined, "country")
+	required.check(location.City.Defined, "city")
+	required.check(location.Distance.Defined, "distance")
+	if err := required.err(); err != nil {
+		return err
+	}
+	if err := model.ValidateLocation(location); err != nil {
+		return err
+	}
+	if location.ID.V < 0 {
+		return ErrInvalidID
+	}
+	if s.locationsByID.get(location.ID.V) != nil {
+		return ErrAlreadyExist
 	}
-	s.mxVisitsByLocationID.Unlock()
 	return nil
 }
 
 // GetLocationByID find location by id
 func (s *Store) GetLocationByID(id int32) (*model.Location, bool) {
 	var result model.Location
-	s.mxLocationsByID.RLock()
-	defer s.mxLocationsByID.RUnlock()
-	if id >= int32(len(s.locationsByID)) || id < 0 {
-		return nil, false
-	}
-	l := s.locationsByID[id]
+	mx := s.shard(locationLocks, id)
+	mx.RLock()
+	defer mx.RUnlock()
+	l := s.locationsByID.get(id)
 	if l != nil {
 		result = *l
 	}
@@ -165,12 +213,20 @@ func (s *Store) GetLocationByID(id int32) (*model.Location, bool) {
 
 func (s *Store) GetLocationAvg(id int32, fromDate *int64, toDate *int64, fromAge *int64, toAge *int64, gender *string) (float64, bool) {
 	var avg float64
-	s.mxVisitsByLocationID.RLock()
-	defer s.mxVisitsByLocationID.RUnlock()
-	if id >= int32(len(s.visitsByLocationID)) || id < 0 {
+	locks := locationVisitsLocks(id, fromAge, toAge, gender)
+	s.acquire(&locks)
+	defer s.release(&locks)
+	if !s.locationExists(id) {
 		return 0, false
 	}
-	vi := s.visitsByLocationID[id]
+	vi := s.visitsByLocationID.get(id)
+	if vi != nil && fromAge == nil && toAge == nil && gender == nil {
+		sum, count := vi.MarkSum(fromDate, toDate)
+		if count > 0 {
+			avg = float64(sum) / float64(count)
+		}
+		return avg + 1e-10, true
+	}
 	if vi != nil {
 		visits := vi.GetByAgeAndGender(fromDate, toDate, fromAge, toAge, gender)
 		if len(visits) > 0 {
@@ -184,27 +240,31 @@ func (s *Store) GetLocationAvg(id int32, fromDate *int64, toDate *int64, fromAge
 	return 0, false
 }
 
+// locationExists checks id without copying the location (visits of deleted locations are kept by CascadeOrphan),
+// the caller holds the location shard
+func (s *Store) locationExists(id int32) bool {
+	return s.locationsByID.get(id) != nil
+}
+
 // UpdateLocationByID updates location with id by user
 // if location does not exist returns error
 func (s *Store) UpdateLocationByID(id int32, location model.Location) error {
-	s.mxLocationsByID.Lock()
-	defer s.mxLocationsByID.Unlock()
-	if id >= int32(len(s.locationsByID)) || id < 0 {
-		return ErrDoesNotExist
-	}
-	l := s.locationsByID[id]
-	if l == nil {
-		return ErrDoesNotExist
-	}
-	if location.ID.Defined {
-		return ErrIDInUpdate
+	return s.commit(&walRecord{op: walUpdateLocation, id: id, location: &location})
+}
+
+func (s *Store) updateLocationByID(id int32, location model.Location) error {
+	if err := s.checkUpdateLocation(id, &location); err != nil {
+		return err
 	}
+	l := s.locationsByID.get(id)
+	s.unindexLocation(l)
 	if location.City.Defined {
 		l.City = location.City
 	}
 	if location.Country.Defined {
 		l.Country = location.Country
 	}
+	s.indexLocation(l)
 	if location.Distance.Defined {
 		l.Distance = location.Distance
 	}
@@ -214,53 +274,50 @@ func (s *Store) UpdateLocationByID(id int32, location model.Location) error {
 	return nil
 }
 
+// checkUpdateLocation returns the error updateLocationByID fails with
+func (s *Store) checkUpdateLocation(id int32, location *model.Location) error {
+	if s.locationsByID.get(id) == nil {
+		return ErrDoesNotExist
+	}
+	if location.ID.Defined {
+		return ErrIDInUpdate
+	}
+	return model.ValidateLocation(location)
+}
+
 func (s *Store) addVisitToVisitsByLocationID(visit *model.Visit) {
-	s.mxVisitsByLocationID.Lock()
-	defer s.mxVisitsByLocationID.Unlock()
-	if visit.LocationID.V >= int32(len(s.visitsByLocationID)) || visit.LocationID.V < 0 {
+	if visit.LocationID.V < 0 {
 		return
 	}
-	vi := s.visitsByLocationID[visit.LocationID.V]
+	vi := s.visitsByLocationID.get(visit.LocationID.V)
 	if vi == nil {
-		vi = NewVisitIndex()
-		s.visitsByLocationID[visit.LocationID.V] = vi
+		vi = NewLocationVisitIndex()
+		s.visitsByLocationID.set(visit.LocationID.V, vi)
 	}
 	vi.Add(visit)
 }
 
 func (s *Store) addVisitToVisitsByUserID(visit *model.Visit) {
-	s.mxVisitsByUserID.Lock()
-	defer s.mxVisitsByUserID.Unlock()
-	if visit.UserID.V >= int32(len(s.visitsByUserID)) || visit.UserID.V < 0 {
+	if visit.UserID.V < 0 {
 		return
 	}
-	visitIndex := s.visitsByUserID[visit.UserID.V]
+	visitIndex := s.visitsByUserID.get(visit.UserID.V)
 	if visitIndex == nil {
 		visitIndex = NewVisitIndex()
-		s.visitsByUserID[visit.UserID.V] = visitIndex
+		s.visitsByUserID.set(visit.UserID.V, visitIndex)
 	}
 	visitIndex.Add(visit)
 }
 
 func (s *Store) updateLocationLink(visit *model.Visit) {
-	s.mxLocationsByID.Lock()
-	defer s.mxLocationsByID.Unlock()
-	if visit.LocationID.V >= int32(len(s.locationsByID)) || visit.LocationID.V < 0 {
-		return
-	}
-	location := s.locationsByID[visit.LocationID.V]
+	location := s.locationsByID.get(visit.LocationID.V)
 	if location != nil {
 		visit.Location = location
 	}
 }
 
 func (s *Store) updateUserLink(visit *model.Visit) {
-	s.mxUsersByID.Lock()
-	defer s.mxUsersByID.Unlock()
-	if visit.UserID.V >= int32(len(s.usersByID)) || visit.UserID.V < 0 {
-		return
-	}
-	user := s.usersByID[visit.UserID.V]
+	user := s.usersByID.get(visit.UserID.V)
 	if user != nil {
 		visit.User = user
 	}
@@ -268,21 +325,14 @@ func (s *Store) updateUserLink(visit *model.Visit) {
 
 // AddVisit adds new visit to the store
 func (s *Store) AddVisit(visit model.Visit) error {
-	if !visit.ID.Defined || !visit.LocationID.Defined ||
-		!visit.UserID.Defined || !visit.Mark.Defined || !visit.VisitedAt.Defined {
-		return ErrRequiredFields
-	}
-
-	s.mxVisitsByID.Lock()
-	defer s.mxVisitsByID.Unlock()
-	if visit.ID.V >= int32(len(s.visitsByID)) || visit.ID.V < 0 {
-		return ErrDoesNotExist
-	}
+	return s.commit(&walRecord{op: walAddVisit, visit: &visit})
+}
 
-	if s.visitsByID[visit.ID.V] != nil {
-		return ErrAlreadyExist
+func (s *Store) addVisit(visit model.Visit) error {
+	if err := s.checkAddVisit(&visit); err != nil {
+		return err
 	}
-	s.visitsByID[visit.ID.V] = &visit
+	s.visitsByID.set(visit.ID.V, &visit)
 
 	s.addVisitToVisitsByLocationID(&visit)
 	s.addVisitToVisitsByUserID(&visit)
@@ -294,30 +344,53 @@ func (s *Store) AddVisit(visit model.Visit) error {
 	return nil
 }
 
+// checkAddVisit returns the error addVisit fails with
+func (s *Store) checkAddVisit(visit *model.Visit) error {
+	var required requiredFields
+	required.check(visit.ID.Defined, "id")
+	required.check(visit.LocationID.Defined, "location")
+	required.check(visit.UserID.Defined, "user")
+	required.check(visit.VisitedAt.Defined, "visited_at")
+	required.check(visit.Mark.Defined, "mark")
+	if err := required.err(); err != nil {
+		return err
+	}
+	if err := model.ValidateVisit(visit); err != nil {
+		return err
+	}
+	if visit.ID.V < 0 {
+		return ErrInvalidID
+	}
+	if s.visitsByID.get(visit.ID.V) != nil {
+		return ErrAlreadyExist
+	}
+	return nil
+}
+
 // GetVisitByID find visit by id
 func (s *Store) GetVisitByID(id int32) (*model.Visit, bool) {
 	var result model.Visit
-	s.mxVisitsByID.RLock()
-	defer s.mxVisitsByID.RUnlock()
-	if id >= int32(len(s.visitsByID)) || id < 0 {
-		return nil, false
-	}
-	v := s.visitsByID[id]
+	mx := s.shard(visitLocks, id)
+	mx.RLock()
+	defer mx.RUnlock()
+	v := s.visitsByID.get(id)
 	if v != nil {
 		result = *v
 	}
 	return &result, v != nil
 }
 
-func (s *Store) GetVisitsByUserID(id int32, fromDate *int64, toDate *int64, country *string, toDistance *int32) (*model.UserVisitArray, bool) {
-	s.mxVisitsByUserID.RLock()
-	defer s.mxVisitsByUserID.RUnlock()
-	if id >= int32(len(s.visitsByUserID)) || id < 0 {
+// GetVisitsByUserID returns the page of the user visits (all of them if page is nil)
+func (s *Store) GetVisitsByUserID(id int32, fromDate *int64, toDate *int64, country *string, toDistance *int32, page *Page) (*model.UserVisitArray, bool) {
+	locks := userVisitsLocks(id)
+	s.acquire(&locks)
+	defer s.release(&locks)
+	if !s.userExists(id) {
 		return nil, false
 	}
-	visitIndex := s.visitsByUserID[id]
+	visitIndex := s.visitsByUserID.get(id)
 	if visitIndex != nil {
-		visits := visitIndex.GetByCountryAndDistance(fromDate, toDate, country, toDistance)
+		visits := visitIndex.GetByCountryAndDistance(fromDate, toDate, country, toDistance, page)
 		return &visits, true
 	}
 	return nil, false
@@ -326,75 +399,107 @@ func (s *Store) GetVisitsByUserID(id int32, fromDate *int64, toDate *int64, coun
 // UpdateVisitByID updates visit with id by visit
 // if visit does not exist returns error
 func (s *Store) UpdateVisitByID(id int32, visit model.Visit) error {
-	s.mxVisitsByID.Lock()
-	defer s.mxVisitsByID.Unlock()
-	if id >= int32(len(s.visitsByID)) || id < 0 {
-		return ErrDoesNotExist
+	return s.commit(&walRecord{op: walUpdateVisit, id: id, visit: &visit})
+}
+
+func (s *Store) updateVisitByID(id int32, visit model.Visit) error {
+	if err := s.checkUpdateVisit(id, &visit); err != nil {
+		return err
 	}
-	v := s.visitsByID[id]
-	if v == nil {
+	v := s.visitsByID.get(id)
+
+	if visit.LocationID.Defined && v.LocationID.V != visit.LocationID.V {
+		// transfer from one location VisitIndex to another
+		if vl := s.visitsByLocationID.get(v.LocationID.V); vl != nil {
+			vl.Remove(v)
+		}
+		v.LocationID = visit.LocationID
+		v.Location = nil
+		s.updateLocationLink(v)
+		s.addVisitToVisitsByLocationID(v)
+	}
+	if visit.Mark.Defined && v.Mark.V != visit.Mark.V {
+		// update aggregated marks of the location
+		vl := s.visitsByLocationID.get(v.LocationID.V)
+		if vl != nil {
+			vl.Remove(v)
+		}
+		v.Mark = visit.Mark
+		if vl != nil {
+			vl.Add(v)
+		}
+	}
+	if visit.UserID.Defined && v.UserID.V != visit.UserID.V {
+		// transfer from one user VisitIndex to another
+		if vi := s.visitsByUserID.get(v.UserID.V); vi != nil {
+			vi.Remove(v)
+		}
+		v.UserID = visit.UserID
+		v.User = nil
+		s.updateUserLink(v)
+		s.addVisitToVisitsByUserID(v)
+	}
+	if visit.VisitedAt.Defined && v.VisitedAt.V != visit.VisitedAt.V {
+		// delete and insert the visit again to keep VisitIndex ordered
+		vi := s.visitsByUserID.get(v.UserID.V)
+		vl := s.visitsByLocationID.get(v.LocationID.V)
+		if vi != nil {
+			vi.Remove(v)
+		}
+		if vl != nil {
+			vl.Remove(v)
+		}
+		v.VisitedAt = visit.VisitedAt
+		s.addVisitToVisitsByUserID(v)
+		s.addVisitToVisitsByLocationID(v)
+	}
+	return nil
+}
+
+// checkUpdateVisit returns the error updateVisitByID fails with
+func (s *Store) checkUpdateVisit(id int32, visit *model.Visit) error {
+	if s.visitsByID.get(id) == nil {
 		return ErrDoesNotExist
 	}
 	if visit.ID.Defined {
 		return ErrIDInUpdate
 	}
+	return model.ValidateVisit(visit)
+}
 
-	if visit.LocationID.Defined {
-		if v.LocationID.V != visit.LocationID.V {
-			// remove from the old id position
-			s.mxVisitsByLocationID.Lock()
-			vl := s.visitsByLocationID[v.LocationID.V]
-			if vl != nil {
-				vl.Remove(v)
-			}
-			s.mxVisitsByLocationID.Unlock()
-
-			v.LocationID = visit.LocationID
-			s.updateLocationLink(v)
+// userVisitsLocks are the read locks of the user visits together with their locations
+func userVisitsLocks(id int32) lockSet {
+	var l lockSet
+	l.rlock(userIndexLocks, id)
+	l.rlockAll(locationLocks)
+	l.rlock(userLocks, id)
+	return l
+}
 
-			// add again with updated id
-			s.addVisitToVisitsByLocationID(v)
-		}
-	}
-	if visit.Mark.Defined {
-		v.Mark = visit.Mark
+// locationVisitsLocks are the read locks of the location visits,
+// users of the visits are read only by the age and gender filters
+func locationVisitsLocks(id int32, fromAge *int64, toAge *int64, gender *string) lockSet {
+	var l lockSet
+	l.rlock(locationIndexLocks, id)
+	l.rlock(locationLocks, id)
+	if fromAge != nil || toAge != nil || gender != nil {
+		l.rlockAll(userLocks)
 	}
-	if visit.UserID.Defined {
-		if v.UserID.V != visit.UserID.V {
-			s.mxVisitsByUserID.Lock()
-			// transfer from one VisitIndex to another
-			vi := s.visitsByUserID[v.UserID.V]
-			if vi != nil {
-				vi.Remove(v)
-			}
-			s.mxVisitsByUserID.Unlock()
+	return l
+}
 
-			v.UserID = visit.UserID
-			s.updateUserLink(v)
-			s.addVisitToVisitsByUserID(v)
-		}
-	}
-	if visit.VisitedAt.Defined {
-		if v.VisitedAt.V != visit.VisitedAt.V {
-			// Delete and insert again visit to the VisitIndex (tree rebalancing)
-			s.mxVisitsByLocationID.Lock()
-			s.mxVisitsByUserID.Lock()
-			vi1 := s.visitsByUserID[v.UserID.V]
-			vi2 := s.visitsByLocationID[v.LocationID.V]
-			if vi1 != nil {
-				vi1.Remove(v)
-			}
-			if vi2 != nil {
-				vi2.Remove(v)
-			}
-			s.mxVisitsByLocationID.Unlock()
-			s.mxVisitsByUserID.Unlock()
+// requiredFields collects ErrRequiredFields of the undefined fields of a new entity
+type requiredFields model.FieldErrors
 
-			v.VisitedAt = visit.VisitedAt
+func (r *requiredFields) check(defined bool, field string) {
+	if !defined {
+		*r = append(*r, &model.FieldError{Field: field, Err: ErrRequiredFields})
+	}
+}
 
-			s.addVisitToVisitsByUserID(v)
-			s.addVisitToVisitsByLocationID(v)
-		}
+func (r requiredFields) err() error {
+	if len(r) == 0 {
+		return nil
 	}
-	return nil
+	return model.FieldErrors(r)
 }
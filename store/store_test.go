@@ -0,0 +1,85 @@
+package store
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/la0rg/highloadcup/clock"
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func TestStore_RequiredFields(t *testing.T) {
+	s := NewStore()
+	incomplete := testUser(1)
+	incomplete.FirstName, incomplete.LastName, incomplete.BirthDate = opt.String{}, opt.String{}, opt.Int64{}
+	err := s.AddUser(incomplete)
+	errs, ok := err.(model.FieldErrors)
+	if !ok {
+		t.Fatalf("expected field errors, got %v", err)
+	}
+	expected := []string{"first_name", "last_name", "birth_date"}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d field errors, got %v", len(expected), errs)
+	}
+	for i, e := range errs {
+		if e.Field != expected[i] || e.Err != ErrRequiredFields {
+			t.Errorf("expected %s required, got %v", expected[i], e)
+		}
+	}
+	if _, ok := s.GetUserByID(1); ok {
+		t.Error("incomplete user was added")
+	}
+
+	err = s.AddVisit(model.Visit{ID: opt.OInt32(1), UserID: opt.OInt32(1), LocationID: opt.OInt32(1), VisitedAt: opt.OInt64(0)})
+	if errs, ok := err.(model.FieldErrors); !ok || len(errs) != 1 || errs[0].Field != "mark" {
+		t.Errorf("expected mark required, got %v", err)
+	}
+}
+
+func TestStore_AgeFilters(t *testing.T) {
+	s := NewStore()
+	s.AddLocation(testLocation(1))
+	births := []time.Time{
+		time.Date(1996, time.February, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(1996, time.March, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1996, time.February, 28, 12, 0, 0, 0, time.UTC),
+	}
+	marks := []uint8{1, 2, 4}
+	for i, birth := range births {
+		id := opt.OInt32(int32(i + 1))
+		u := testUser(id.V)
+		u.BirthDate = opt.OInt64(birth.Unix())
+		s.AddUser(u)
+		s.AddVisit(model.Visit{ID: id, UserID: id, LocationID: opt.OInt32(1), VisitedAt: opt.OInt64(1), Mark: opt.OUint8(marks[i])})
+	}
+
+	// it is already March 1 in Moscow
+	now := time.Date(2017, time.February, 28, 22, 0, 0, 0, time.UTC)
+	msk := time.FixedZone("MSK", 3*60*60)
+	from := func(loc *time.Location) *int64 {
+		b := clock.AgeBoundary(now, 21, loc)
+		return &b
+	}
+	to := func(loc *time.Location) *int64 {
+		b := clock.AgeBoundary(now, 21, loc) - 1
+		return &b
+	}
+	cases := []struct {
+		name     string
+		from, to *int64
+		avg      float64
+	}{
+		{"21 and older in UTC", from(time.UTC), nil, 4},
+		{"younger than 21 in UTC", nil, to(time.UTC), 1.5},
+		{"21 and older in Moscow", from(msk), nil, 7.0 / 3},
+		{"younger than 21 in Moscow", nil, to(msk), 0},
+	}
+	for _, c := range cases {
+		avg, ok := s.GetLocationAvg(1, nil, nil, c.from, c.to, nil)
+		if !ok || math.Abs(avg-c.avg) > 1e-6 {
+			t.Errorf("%s: expected avg %v, got %v", c.name, c.avg, avg)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package store
+
+import "github.com/la0rg/highloadcup/model"
+
+// GetUserSummary aggregates all the visits of the user
+func (s *Store) GetUserSummary(id int32) (*model.UserSummary, bool) {
+	locks := userVisitsLocks(id)
+	s.acquire(&locks)
+	defer s.release(&locks)
+	if !s.userExists(id) {
+		return nil, false
+	}
+	vi := s.visitsByUserID.get(id)
+	if vi == nil {
+		return nil, false
+	}
+	return vi.Summary(), true
+}
+
+// Summary walks over the visits without copying them
+func (vi *VisitIndex) Summary() *model.UserSummary {
+	summary := &model.UserSummary{}
+	var sum int64
+	countries := make(map[string]struct{})
+	locations := make(map[int32]struct{})
+	var first, last int64
+	vi.ApplyToAll(func(visit *model.Visit) {
+		// visits are ordered by date
+		if summary.Visits == 0 {
+			first = visit.VisitedAt.V
+		}
+		last = visit.VisitedAt.V
+		summary.Visits++
+		sum += int64(visit.Mark.V)
+		locations[visit.LocationID.V] = struct{}{}
+		// visits of a deleted location are counted, but have no country and distance
+		if visit.Location != nil {
+			countries[visit.Location.Country.V] = struct{}{}
+			if visit.Location.Distance.V > summary.MaxDistance {
+				summary.MaxDistance = visit.Location.Distance.V
+			}
+		}
+	})
+	summary.Countries = len(countries)
+	summary.Locations = len(locations)
+	if summary.Visits > 0 {
+		summary.Avg = float64(sum) / float64(summary.Visits)
+		summary.FirstVisitedAt = &first
+		summary.LastVisitedAt = &last
+	}
+	return summary
+}
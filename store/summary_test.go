@@ -0,0 +1,56 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func TestStore_UserSummary(t *testing.T) {
+	s := NewStore()
+	addTestEntities(s, 1, 2, 3)
+	s.SetCascadePolicy(CascadeOrphan)
+	s.UpdateLocationByID(2, model.Location{Country: opt.OString("d"), Distance: opt.OInt32(30)})
+	s.UpdateLocationByID(3, model.Location{Distance: opt.OInt32(50)})
+	visits := []struct {
+		location  int32
+		visitedAt int64
+		mark      uint8
+	}{
+		{1, 300, 4}, {2, 100, 2}, {1, 200, 3}, {3, 400, 5},
+	}
+	for i, v := range visits {
+		s.AddVisit(model.Visit{ID: opt.OInt32(int32(i + 1)), UserID: opt.OInt32(1), LocationID: opt.OInt32(v.location),
+			VisitedAt: opt.OInt64(v.visitedAt), Mark: opt.OUint8(v.mark)})
+	}
+
+	check := func(name string, countries int, maxDistance int32) {
+		summary, ok := s.GetUserSummary(1)
+		if !ok {
+			t.Fatalf("%s: user is not found", name)
+		}
+		if summary.Visits != 4 || summary.Locations != 3 || summary.Avg != 3.5 ||
+			summary.FirstVisitedAt == nil || *summary.FirstVisitedAt != 100 ||
+			summary.LastVisitedAt == nil || *summary.LastVisitedAt != 400 {
+			t.Errorf("%s: unexpected visits summary %+v", name, summary)
+		}
+		if summary.Countries != countries || summary.MaxDistance != maxDistance {
+			t.Errorf("%s: expected %d countries and max distance %d, got %+v", name, countries, maxDistance, summary)
+		}
+	}
+	check("all locations", 2, 50)
+	// visits of the deleted locations are counted without their countries and distances
+	s.DeleteLocation(3)
+	check("farthest location deleted", 2, 30)
+	s.DeleteLocation(2)
+	check("only country deleted", 1, 1)
+
+	summary, ok := s.GetUserSummary(2)
+	if !ok || summary.Visits != 0 || summary.FirstVisitedAt != nil || summary.LastVisitedAt != nil {
+		t.Errorf("expected empty summary of user 2, got %+v, %v", summary, ok)
+	}
+	if _, ok := s.GetUserSummary(4); ok {
+		t.Error("summary of a missing user")
+	}
+}
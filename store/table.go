@@ -0,0 +1,295 @@
+package store
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/la0rg/highloadcup/model"
+)
+
+// Tables map non-negative int32 ids to entities.
+// They are split into fixed-size pages that are allocated on the first write,
+// so the memory is proportional to the used id ranges and there is no upper limit for ids.
+// Pages are grouped into chunks of the table directory, chunks are published atomically,
+// so a page is guarded by the shard lock of its id range only (see locks.go).
+// get returns nil for negative ids, set expects non-negative ones.
+const (
+	pageBits = 12
+	pageSize = 1 << pageBits
+	pageMask = pageSize - 1
+
+	chunkBits = 10
+	chunkSize = 1 << chunkBits
+	chunkMask = chunkSize - 1
+	// dirSize chunks cover all the non-negative int32 ids
+	dirSize = 1 << (31 - pageBits - chunkBits)
+)
+
+// directory keeps the chunks of a table, every chunk is an array of chunkSize page pointers
+type directory struct {
+	mx     sync.Mutex
+	chunks [dirSize]atomic.Value
+	// used is the number of the leading chunks that may be allocated
+	used int32
+}
+
+func (d *directory) chunk(p int) interface{} {
+	return d.chunks[p>>chunkBits].Load()
+}
+
+// grow returns the chunk of page p allocating it by alloc if needed
+func (d *directory) grow(p int, alloc func() interface{}) interface{} {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	c := &d.chunks[p>>chunkBits]
+	if chunk := c.Load(); chunk != nil {
+		return chunk
+	}
+	chunk := alloc()
+	c.Store(chunk)
+	if used := int32(p>>chunkBits) + 1; used > d.used {
+		atomic.StoreInt32(&d.used, used)
+	}
+	return chunk
+}
+
+// forEachChunk calls f for the allocated chunks in the order of ids
+func (d *directory) forEachChunk(f func(interface{})) {
+	used := int(atomic.LoadInt32(&d.used))
+	for i := 0; i < used; i++ {
+		if chunk := d.chunks[i].Load(); chunk != nil {
+			f(chunk)
+		}
+	}
+}
+
+type userChunk [chunkSize]*[pageSize]*model.User
+
+type userTable struct {
+	dir   directory
+	count int64
+}
+
+func (t *userTable) get(id int32) *model.User {
+	if id < 0 {
+		return nil
+	}
+	p := int(id >> pageBits)
+	chunk, _ := t.dir.chunk(p).(*userChunk)
+	if chunk == nil || chunk[p&chunkMask] == nil {
+		return nil
+	}
+	return chunk[p&chunkMask][id&pageMask]
+}
+
+func (t *userTable) set(id int32, u *model.User) {
+	p := int(id >> pageBits)
+	chunk, _ := t.dir.chunk(p).(*userChunk)
+	if chunk == nil {
+		if u == nil {
+			return
+		}
+		chunk = t.dir.grow(p, func() interface{} { return new(userChunk) }).(*userChunk)
+	}
+	page := chunk[p&chunkMask]
+	if page == nil {
+		if u == nil {
+			return
+		}
+		page = new([pageSize]*model.User)
+		chunk[p&chunkMask] = page
+	}
+	old := page[id&pageMask]
+	atomic.AddInt64(&t.count, countDelta(old != nil, u != nil))
+	page[id&pageMask] = u
+}
+
+func (t *userTable) len() int {
+	return int(atomic.LoadInt64(&t.count))
+}
+
+// forEach walks over all the users, the caller holds all the user shards
+func (t *userTable) forEach(f func(*model.User)) {
+	t.dir.forEachChunk(func(c interface{}) {
+		for _, page := range c.(*userChunk) {
+			if page == nil {
+				continue
+			}
+			for _, u := range page {
+				if u != nil {
+					f(u)
+				}
+			}
+		}
+	})
+}
+
+type locationChunk [chunkSize]*[pageSize]*model.Location
+
+type locationTable struct {
+	dir   directory
+	count int64
+}
+
+func (t *locationTable) get(id int32) *model.Location {
+	if id < 0 {
+		return nil
+	}
+	p := int(id >> pageBits)
+	chunk, _ := t.dir.chunk(p).(*locationChunk)
+	if chunk == nil || chunk[p&chunkMask] == nil {
+		return nil
+	}
+	return chunk[p&chunkMask][id&pageMask]
+}
+
+func (t *locationTable) set(id int32, l *model.Location) {
+	p := int(id >> pageBits)
+	chunk, _ := t.dir.chunk(p).(*locationChunk)
+	if chunk == nil {
+		if l == nil {
+			return
+		}
+		chunk = t.dir.grow(p, func() interface{} { return new(locationChunk) }).(*locationChunk)
+	}
+	page := chunk[p&chunkMask]
+	if page == nil {
+		if l == nil {
+			return
+		}
+		page = new([pageSize]*model.Location)
+		chunk[p&chunkMask] = page
+	}
+	old := page[id&pageMask]
+	atomic.AddInt64(&t.count, countDelta(old != nil, l != nil))
+	page[id&pageMask] = l
+}
+
+func (t *locationTable) len() int {
+	return int(atomic.LoadInt64(&t.count))
+}
+
+// forEach walks over all the locations, the caller holds all the location shards
+func (t *locationTable) forEach(f func(*model.Location)) {
+	t.dir.forEachChunk(func(c interface{}) {
+		for _, page := range c.(*locationChunk) {
+			if page == nil {
+				continue
+			}
+			for _, l := range page {
+				if l != nil {
+					f(l)
+				}
+			}
+		}
+	})
+}
+
+type visitChunk [chunkSize]*[pageSize]*model.Visit
+
+type visitTable struct {
+	dir   directory
+	count int64
+}
+
+func (t *visitTable) get(id int32) *model.Visit {
+	if id < 0 {
+		return nil
+	}
+	p := int(id >> pageBits)
+	chunk, _ := t.dir.chunk(p).(*visitChunk)
+	if chunk == nil || chunk[p&chunkMask] == nil {
+		return nil
+	}
+	return chunk[p&chunkMask][id&pageMask]
+}
+
+func (t *visitTable) set(id int32, v *model.Visit) {
+	p := int(id >> pageBits)
+	chunk, _ := t.dir.chunk(p).(*visitChunk)
+	if chunk == nil {
+		if v == nil {
+			return
+		}
+		chunk = t.dir.grow(p, func() interface{} { return new(visitChunk) }).(*visitChunk)
+	}
+	page := chunk[p&chunkMask]
+	if page == nil {
+		if v == nil {
+			return
+		}
+		page = new([pageSize]*model.Visit)
+		chunk[p&chunkMask] = page
+	}
+	old := page[id&pageMask]
+	atomic.AddInt64(&t.count, countDelta(old != nil, v != nil))
+	page[id&pageMask] = v
+}
+
+func (t *visitTable) len() int {
+	return int(atomic.LoadInt64(&t.count))
+}
+
+// forEach walks over all the visits, the caller holds all the visit shards
+func (t *visitTable) forEach(f func(*model.Visit)) {
+	t.dir.forEachChunk(func(c interface{}) {
+		for _, page := range c.(*visitChunk) {
+			if page == nil {
+				continue
+			}
+			for _, v := range page {
+				if v != nil {
+					f(v)
+				}
+			}
+		}
+	})
+}
+
+type indexChunk [chunkSize]*[pageSize]*VisitIndex
+
+type indexTable struct {
+	dir directory
+}
+
+func (t *indexTable) get(id int32) *VisitIndex {
+	if id < 0 {
+		return nil
+	}
+	p := int(id >> pageBits)
+	chunk, _ := t.dir.chunk(p).(*indexChunk)
+	if chunk == nil || chunk[p&chunkMask] == nil {
+		return nil
+	}
+	return chunk[p&chunkMask][id&pageMask]
+}
+
+func (t *indexTable) set(id int32, vi *VisitIndex) {
+	p := int(id >> pageBits)
+	chunk, _ := t.dir.chunk(p).(*indexChunk)
+	if chunk == nil {
+		if vi == nil {
+			return
+		}
+		chunk = t.dir.grow(p, func() interface{} { return new(indexChunk) }).(*indexChunk)
+	}
+	page := chunk[p&chunkMask]
+	if page == nil {
+		if vi == nil {
+			return
+		}
+		page = new([pageSize]*VisitIndex)
+		chunk[p&chunkMask] = page
+	}
+	page[id&pageMask] = vi
+}
+
+func countDelta(was, is bool) int64 {
+	switch {
+	case !was && is:
+		return 1
+	case was && !is:
+		return -1
+	}
+	return 0
+}
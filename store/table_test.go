@@ -0,0 +1,94 @@
+package store
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func TestStore_LargeIDs(t *testing.T) {
+	ids := []int32{0, 1, pageSize, 11000000, math.MaxInt32}
+	s := NewStore()
+	for _, id := range ids {
+		addTestEntities(s, id)
+		visit := model.Visit{ID: opt.OInt32(id), UserID: opt.OInt32(id), LocationID: opt.OInt32(id),
+			VisitedAt: opt.OInt64(1), Mark: opt.OUint8(3)}
+		if err := s.AddVisit(visit); err != nil {
+			t.Fatalf("visit %d: %v", id, err)
+		}
+	}
+	if err := s.UpdateUserByID(math.MaxInt32, model.User{FirstName: opt.OString("x")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := s.WriteSnapshot(&b); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewStore()
+	if err := loaded.ReadSnapshot(bytes.NewReader(b.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range []*Store{s, loaded} {
+		for _, id := range ids {
+			if u, ok := st.GetUserByID(id); !ok || u.ID.V != id {
+				t.Errorf("user %d: got %v", id, u)
+			}
+			if l, ok := st.GetLocationByID(id); !ok || l.ID.V != id {
+				t.Errorf("location %d: got %v", id, l)
+			}
+			if visits, ok := st.GetVisitsByUserID(id, nil, nil, nil, nil, nil); !ok || len(visits.Visits) != 1 {
+				t.Errorf("visits of user %d: got %v", id, visits)
+			}
+			if avg, ok := st.GetLocationAvg(id, nil, nil, nil, nil, nil); !ok || avg < 3 || avg > 3.0001 {
+				t.Errorf("avg of location %d: got %v", id, avg)
+			}
+		}
+		if u, _ := st.GetUserByID(math.MaxInt32); u.FirstName.V != "x" {
+			t.Errorf("update of user %d is lost: %v", math.MaxInt32, u)
+		}
+		if _, ok := st.GetUserByID(11000001); ok {
+			t.Error("user 11000001 exists")
+		}
+	}
+
+	if err := s.DeleteVisit(math.MaxInt32); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.GetVisitByID(math.MaxInt32); ok {
+		t.Errorf("visit %d is not deleted", math.MaxInt32)
+	}
+}
+
+func TestStore_NegativeIDs(t *testing.T) {
+	s := NewStore()
+	addTestEntities(s, 1)
+	for _, id := range []int32{-1, math.MinInt32} {
+		if err := s.AddUser(testUser(id)); err != ErrInvalidID {
+			t.Errorf("user %d: expected ErrInvalidID, got %v", id, err)
+		}
+		if err := s.AddLocation(testLocation(id)); err != ErrInvalidID {
+			t.Errorf("location %d: expected ErrInvalidID, got %v", id, err)
+		}
+		visit := model.Visit{ID: opt.OInt32(id), UserID: opt.OInt32(1), LocationID: opt.OInt32(1),
+			VisitedAt: opt.OInt64(1), Mark: opt.OUint8(3)}
+		if err := s.AddVisit(visit); err != ErrInvalidID {
+			t.Errorf("visit %d: expected ErrInvalidID, got %v", id, err)
+		}
+		if _, ok := s.GetUserByID(id); ok {
+			t.Errorf("user %d exists", id)
+		}
+		if err := s.UpdateUserByID(id, model.User{FirstName: opt.OString("x")}); err != ErrDoesNotExist {
+			t.Errorf("update of user %d: expected ErrDoesNotExist, got %v", id, err)
+		}
+		if err := s.DeleteLocation(id); err != ErrDoesNotExist {
+			t.Errorf("delete of location %d: expected ErrDoesNotExist, got %v", id, err)
+		}
+	}
+	if visits, _ := s.GetVisitsByUserID(1, nil, nil, nil, nil, nil); len(visits.Visits) != 0 {
+		t.Errorf("visits of the negative ids are added: %v", visits.Visits)
+	}
+}
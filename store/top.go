@@ -0,0 +1,140 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/la0rg/highloadcup/model"
+)
+
+// TopFilter selects and orders locations for GetTopLocations
+type TopFilter struct {
+	Country  *string
+	City     *string
+	FromDate *int64
+	ToDate   *int64
+	FromAge  *int64
+	ToAge    *int64
+	Gender   *string
+	// MinVisits is the minimum number of the matching visits of a location
+	MinVisits int
+	// Limit is the maximum number of the locations, 0 means no limit
+	Limit int
+	// ByVisits orders locations by the number of the visits instead of the average mark
+	ByVisits bool
+}
+
+// GetTopLocations ranks locations by the average mark (or the number of the visits) in descending order.
+// Locations are selected by country and city indexes, the average of the unfiltered visits
+// is taken from the aggregated marks, so only age and gender filters require scanning the visits.
+func (s *Store) GetTopLocations(f TopFilter) model.LocationRankArray {
+	var locks lockSet
+	locks.rlockAll(locationIndexLocks)
+	locks.rlockAll(locationLocks)
+	if f.FromAge != nil || f.ToAge != nil || f.Gender != nil {
+		locks.rlockAll(userLocks)
+	}
+	locks.rlock(locationSetLocks, 0)
+	s.acquire(&locks)
+	defer s.release(&locks)
+
+	ranks := make([]model.LocationRank, 0)
+	rank := func(location *model.Location) {
+		if f.Country != nil && location.Country.V != *f.Country || f.City != nil && location.City.V != *f.City {
+			return
+		}
+		vi := s.visitsByLocationID.get(location.ID.V)
+		if vi == nil {
+			return
+		}
+		var sum int64
+		var count int
+		if f.FromAge == nil && f.ToAge == nil && f.Gender == nil {
+			sum, count = vi.MarkSum(f.FromDate, f.ToDate)
+		} else {
+			visits := vi.GetByAgeAndGender(f.FromDate, f.ToDate, f.FromAge, f.ToAge, f.Gender)
+			for i := range visits {
+				sum += int64(visits[i].Mark.V)
+			}
+			count = len(visits)
+		}
+		if count == 0 || count < f.MinVisits {
+			return
+		}
+		ranks = append(ranks, model.LocationRank{
+			ID:      location.ID.V,
+			Place:   location.Place.V,
+			Country: location.Country.V,
+			City:    location.City.V,
+			Avg:     float64(sum) / float64(count),
+			Visits:  count,
+		})
+	}
+
+	switch {
+	case f.Country != nil:
+		s.locationsIn(s.locationsByCountry[*f.Country], rank)
+	case f.City != nil:
+		s.locationsIn(s.locationsByCity[*f.City], rank)
+	default:
+		s.locationsByID.forEach(rank)
+	}
+
+	sort.Slice(ranks, func(i, j int) bool {
+		a, b := ranks[i], ranks[j]
+		if f.ByVisits && a.Visits != b.Visits {
+			return a.Visits > b.Visits
+		}
+		if a.Avg != b.Avg {
+			return a.Avg > b.Avg
+		}
+		if a.Visits != b.Visits {
+			return a.Visits > b.Visits
+		}
+		return a.ID < b.ID
+	})
+	if f.Limit > 0 && len(ranks) > f.Limit {
+		ranks = ranks[:f.Limit]
+	}
+	return model.LocationRankArray{Locations: ranks}
+}
+
+func (s *Store) locationsIn(ids map[int32]struct{}, f func(*model.Location)) {
+	for id := range ids {
+		if location := s.locationsByID.get(id); location != nil {
+			f(location)
+		}
+	}
+}
+
+// indexLocation adds location to the country and city indexes, caller holds the location set lock
+func (s *Store) indexLocation(location *model.Location) {
+	if s.locationsByCountry == nil {
+		s.locationsByCountry = make(map[string]map[int32]struct{})
+		s.locationsByCity = make(map[string]map[int32]struct{})
+	}
+	addToSet(s.locationsByCountry, location.Country.V, location.ID.V)
+	addToSet(s.locationsByCity, location.City.V, location.ID.V)
+}
+
+// unindexLocation removes location from the country and city indexes, caller holds the location set lock
+func (s *Store) unindexLocation(location *model.Location) {
+	removeFromSet(s.locationsByCountry, location.Country.V, location.ID.V)
+	removeFromSet(s.locationsByCity, location.City.V, location.ID.V)
+}
+
+func addToSet(sets map[string]map[int32]struct{}, key string, id int32) {
+	set := sets[key]
+	if set == nil {
+		set = make(map[int32]struct{})
+		sets[key] = set
+	}
+	set[id] = struct{}{}
+}
+
+func removeFromSet(sets map[string]map[int32]struct{}, key string, id int32) {
+	set := sets[key]
+	delete(set, id)
+	if len(set) == 0 {
+		delete(sets, key)
+	}
+}
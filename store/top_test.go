@@ -0,0 +1,68 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func topIDs(top model.LocationRankArray) []int32 {
+	ids := make([]int32, 0, len(top.Locations))
+	for _, l := range top.Locations {
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
+
+func TestStore_TopLocations(t *testing.T) {
+	s := NewStore()
+	addTestEntities(s, 1, 2, 3, 4)
+	s.SetCascadePolicy(CascadeDelete)
+	// location: marks of its visits
+	marks := map[int32][]uint8{1: {5, 5}, 2: {4, 4, 4}, 3: {2, 2, 2, 2}, 4: {5}}
+	id := int32(1)
+	for location := int32(1); location <= 4; location++ {
+		for _, mark := range marks[location] {
+			s.AddVisit(model.Visit{ID: opt.OInt32(id), UserID: opt.OInt32(1), LocationID: opt.OInt32(location),
+				VisitedAt: opt.OInt64(int64(id)), Mark: opt.OUint8(mark)})
+			id++
+		}
+	}
+
+	c, d, x := "c", "d", "x"
+	check := func(name string, f TopFilter, expected []int32) {
+		if ids := topIDs(s.GetTopLocations(f)); !reflect.DeepEqual(ids, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, ids)
+		}
+	}
+	// the same average is ordered by the number of the visits
+	check("avg", TopFilter{}, []int32{1, 4, 2, 3})
+	check("min visits", TopFilter{MinVisits: 2}, []int32{1, 2, 3})
+	check("count", TopFilter{ByVisits: true}, []int32{3, 2, 1, 4})
+	check("limit", TopFilter{Limit: 2}, []int32{1, 4})
+	check("count and limit", TopFilter{ByVisits: true, Limit: 1}, []int32{3})
+	check("country", TopFilter{Country: &c}, []int32{1, 4, 2, 3})
+
+	top := s.GetTopLocations(TopFilter{Limit: 1})
+	if l := top.Locations[0]; l.Avg != 5 || l.Visits != 2 || l.Country != "c" || l.Place != "p" {
+		t.Errorf("unexpected rank %+v", l)
+	}
+
+	s.UpdateLocationByID(4, model.Location{Country: opt.OString("d")})
+	s.UpdateLocationByID(3, model.Location{City: opt.OString("x")})
+	check("country after update", TopFilter{Country: &c}, []int32{1, 2, 3})
+	check("new country", TopFilter{Country: &d}, []int32{4})
+	check("city after update", TopFilter{City: &x}, []int32{3})
+	check("old city", TopFilter{City: &c}, []int32{1, 4, 2})
+
+	s.DeleteLocation(2)
+	s.DeleteLocation(4)
+	check("country after delete", TopFilter{Country: &c}, []int32{1, 3})
+	check("deleted country", TopFilter{Country: &d}, []int32{})
+	if _, ok := s.locationsByCountry[d]; ok {
+		t.Error("empty country is kept in the index")
+	}
+	check("all after delete", TopFilter{}, []int32{1, 3})
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrTxDone = errors.New("Transaction is already committed or rolled back")
+
+// TxError is returned by Tx.Commit when one of the staged mutations fails
+type TxError struct {
+	// Op is the position of the failed mutation in the transaction
+	Op  int
+	Err error
+}
+
+func (e *TxError) Error() string {
+	return "transaction operation " + strconv.Itoa(e.Op) + ": " + e.Err.Error()
+}
+
+// Tx stages creates and updates of the entities and applies them all or none of them.
+// Nothing is changed until Commit, so a transaction that is not needed anymore is just rolled back.
+// Tx is not safe for concurrent use.
+type Tx struct {
+	s    *Store
+	recs []*walRecord
+	done bool
+}
+
+// Begin starts a transaction
+func (s *Store) Begin() *Tx {
+	return &Tx{s: s}
+}
+
+// AddUser stages a new user
+func (tx *Tx) AddUser(user model.User) {
+	tx.recs = append(tx.recs, &walRecord{op: walAddUser, user: &user})
+}
+
+// UpdateUserByID stages an update of the user with id
+func (tx *Tx) UpdateUserByID(id int32, user model.User) {
+	tx.recs = append(tx.recs, &walRecord{op: walUpdateUser, id: id, user: &user})
+}
+
+// AddLocation stages a new location
+func (tx *Tx) AddLocation(location model.Location) {
+	tx.recs = append(tx.recs, &walRecord{op: walAddLocation, location: &location})
+}
+
+// UpdateLocationByID stages an update of the location with id
+func (tx *Tx) UpdateLocationByID(id int32, location model.Location) {
+	tx.recs = append(tx.recs, &walRecord{op: walUpdateLocation, id: id, location: &location})
+}
+
+// AddVisit stages a new visit
+func (tx *Tx) AddVisit(visit model.Visit) {
+	tx.recs = append(tx.recs, &walRecord{op: walAddVisit, visit: &visit})
+}
+
+// UpdateVisitByID stages an update of the visit with id
+func (tx *Tx) UpdateVisitByID(id int32, visit model.Visit) {
+	tx.recs = append(tx.recs, &walRecord{op: walUpdateVisit, id: id, visit: &visit})
+}
+
+// Commit applies the staged mutations in order while holding all the store locks,
+// so readers see either none or all of them.
+// If a mutation fails, the applied ones are reverted and *TxError is returned.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	if len(tx.recs) == 0 {
+		return nil
+	}
+	return tx.s.commit(&walRecord{op: walTx, tx: tx.recs})
+}
+
+// Rollback drops the staged mutations
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.recs = nil
+	return nil
+}
+
+// applyTx applies the records in order and reverts the applied ones on the first error,
+// events of the records are appended to events (if not nil) only if all of them are applied.
+// It returns the mutations that revert the transaction (see revertTx).
+// The caller holds all the store locks.
+func (s *Store) applyTx(recs []*walRecord, events *[]Event) ([]*walRecord, error) {
+	undo := make([]*walRecord, 0, len(recs))
+	n := 0
+	if events != nil {
+		n = len(*events)
+	}
+	for i, rec := range recs {
+		revert := s.revertRecord(rec)
+		if err := s.applyRecord(rec, events); err != nil {
+			if events != nil {
+				*events = (*events)[:n]
+			}
+			s.revertTx(undo)
+			return nil, &TxError{Op: i, Err: err}
+		}
+		if revert == nil {
+			// stageable mutations are revertible, this is a bug
+			log.Errorf("Transaction operation %d can't be reverted", i)
+			continue
+		}
+		undo = append(undo, revert)
+	}
+	return undo, nil
+}
+
+// revertTx applies the mutations returned by applyTx in the reverse order
+func (s *Store) revertTx(undo []*walRecord) {
+	for j := len(undo) - 1; j >= 0; j-- {
+		if err := s.apply(undo[j]); err != nil {
+			log.Errorf("Could not revert transaction operation %d: %v", j, err)
+		}
+	}
+}
+
+// revertRecord returns the mutation that cancels rec, it is called before rec is applied
+func (s *Store) revertRecord(rec *walRecord) *walRecord {
+	switch rec.op {
+	case walAddUser:
+		// visits added before the user stay in place
+		return &walRecord{op: walDeleteUser, id: rec.user.ID.V, cascade: CascadeOrphan}
+	case walAddLocation:
+		return &walRecord{op: walDeleteLocation, id: rec.location.ID.V, cascade: CascadeOrphan}
+	case walAddVisit:
+		return &walRecord{op: walDeleteVisit, id: rec.visit.ID.V}
+	case walUpdateUser:
+		if u := s.usersByID.get(rec.id); u != nil {
+			old := *u
+			old.ID = opt.Int32{}
+			return &walRecord{op: walUpdateUser, id: rec.id, user: &old}
+		}
+	case walUpdateLocation:
+		if l := s.locationsByID.get(rec.id); l != nil {
+			old := *l
+			old.ID = opt.Int32{}
+			return &walRecord{op: walUpdateLocation, id: rec.id, location: &old}
+		}
+	case walUpdateVisit:
+		if v := s.visitsByID.get(rec.id); v != nil {
+			old := model.Visit{LocationID: v.LocationID, UserID: v.UserID, VisitedAt: v.VisitedAt, Mark: v.Mark}
+			return &walRecord{op: walUpdateVisit, id: rec.id, visit: &old}
+		}
+	}
+	// the mutation is going to fail without changes
+	return nil
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+func newTxTestStore() *Store {
+	s := NewStore()
+	addTestEntities(s, 1, 2)
+	for i := int32(1); i <= 10; i++ {
+		s.AddVisit(model.Visit{ID: opt.OInt32(i), UserID: opt.OInt32(1), LocationID: opt.OInt32(1),
+			VisitedAt: opt.OInt64(int64(i)), Mark: opt.OUint8(2)})
+	}
+	return s
+}
+
+func TestTx_CommitAndRollback(t *testing.T) {
+	s := newTxTestStore()
+
+	tx := s.Begin()
+	tx.UpdateVisitByID(1, model.Visit{UserID: opt.OInt32(2), LocationID: opt.OInt32(2)})
+	tx.UpdateUserByID(2, model.User{FirstName: opt.OString("x")})
+	tx.AddVisit(model.Visit{ID: opt.OInt32(11), UserID: opt.OInt32(2), LocationID: opt.OInt32(2),
+		VisitedAt: opt.OInt64(1), Mark: opt.OUint8(4)})
+	tx.UpdateLocationByID(3, model.Location{City: opt.OString("x")})
+	err := tx.Commit()
+	if txErr, ok := err.(*TxError); !ok || txErr.Op != 3 || txErr.Err != ErrDoesNotExist {
+		t.Fatalf("expected error of the operation 3, got %v", err)
+	}
+	if v, _ := s.GetVisitByID(1); v.UserID.V != 1 || v.LocationID.V != 1 {
+		t.Errorf("visit move was not reverted: %v", v)
+	}
+	if u, _ := s.GetUserByID(2); u.FirstName.V != "a" {
+		t.Errorf("user update was not reverted: %v", u)
+	}
+	if _, ok := s.GetVisitByID(11); ok {
+		t.Error("visit create was not reverted")
+	}
+	if visits, _ := s.GetVisitsByUserID(2, nil, nil, nil, nil, nil); len(visits.Visits) != 0 {
+		t.Errorf("expected no visits of user 2, got %v", visits.Visits)
+	}
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("expected ErrTxDone, got %v", err)
+	}
+
+	tx = s.Begin()
+	tx.UpdateVisitByID(1, model.Visit{UserID: opt.OInt32(2), LocationID: opt.OInt32(2)})
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.GetVisitByID(1); v.UserID.V != 1 {
+		t.Errorf("rolled back update was applied: %v", v)
+	}
+
+	tx = s.Begin()
+	tx.UpdateVisitByID(1, model.Visit{UserID: opt.OInt32(2), LocationID: opt.OInt32(2)})
+	tx.UpdateUserByID(2, model.User{FirstName: opt.OString("x")})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if visits, _ := s.GetVisitsByUserID(2, nil, nil, nil, nil, nil); len(visits.Visits) != 1 {
+		t.Errorf("expected 1 visit of user 2, got %v", visits.Visits)
+	}
+	if avg, _ := s.GetLocationAvg(2, nil, nil, nil, nil, nil); math.Abs(avg-2) > 1e-9 {
+		t.Errorf("expected avg 2 of location 2, got %v", avg)
+	}
+}
+
+func TestTx_ReadersSeeAllOrNothing(t *testing.T) {
+	s := newTxTestStore()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// marks of visits 1 and 2 are 1 and 3 or 3 and 1, so the avg is always 2
+			a, b := uint8(1), uint8(3)
+			if i%2 == 0 {
+				a, b = b, a
+			}
+			tx := s.Begin()
+			tx.UpdateVisitByID(1, model.Visit{Mark: opt.OUint8(a), VisitedAt: opt.OInt64(int64(100 + i%7))})
+			tx.UpdateVisitByID(2, model.Visit{Mark: opt.OUint8(b), VisitedAt: opt.OInt64(int64(100 - i%5))})
+			if err := tx.Commit(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		avg, _ := s.GetLocationAvg(1, nil, nil, nil, nil, nil)
+		if math.Abs(avg-2) > 1e-9 {
+			t.Fatalf("expected avg 2, got %v", avg)
+		}
+		visits, _ := s.GetVisitsByUserID(1, nil, nil, nil, nil, nil)
+		if len(visits.Visits) != 10 {
+			t.Fatalf("expected 10 visits, got %d", len(visits.Visits))
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestTx_WALReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "wal")
+
+	s := newTxTestStore()
+	wal, err := OpenWAL(path, SyncNone, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetWAL(wal)
+	tx := s.Begin()
+	tx.UpdateVisitByID(1, model.Visit{UserID: opt.OInt32(2), Mark: opt.OUint8(5)})
+	tx.AddVisit(model.Visit{ID: opt.OInt32(11), UserID: opt.OInt32(2), LocationID: opt.OInt32(2),
+		VisitedAt: opt.OInt64(1), Mark: opt.OUint8(4)})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// failed transactions are not logged
+	tx = s.Begin()
+	tx.UpdateUserByID(1, model.User{FirstName: opt.OString("x")})
+	tx.UpdateUserByID(3, model.User{FirstName: opt.OString("x")})
+	if err := tx.Commit(); err == nil {
+		t.Fatal("update of user 3 succeeded")
+	}
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := newTxTestStore()
+	n, err := replayed.ReplayWAL(path)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 record, got %d, %v", n, err)
+	}
+	if visits, _ := replayed.GetVisitsByUserID(2, nil, nil, nil, nil, nil); len(visits.Visits) != 2 {
+		t.Errorf("expected 2 visits of user 2, got %v", visits.Visits)
+	}
+	if u, _ := replayed.GetUserByID(1); u.FirstName.V != "a" {
+		t.Errorf("failed transaction was replayed: %v", u)
+	}
+}
@@ -1,14 +1,17 @@
 package store
 
 import (
+	"math"
+
 	"github.com/google/btree"
 	"github.com/la0rg/highloadcup/model"
-	"github.com/mailru/easyjson/opt"
 )
 
 type VisitIndex struct {
 	//mx     sync.RWMutex
 	byDate *btree.BTree
+	// marks is maintained for the visits of a location only
+	marks *markTree
 }
 
 type VisitItem struct {
@@ -16,25 +19,41 @@ type VisitItem struct {
 }
 
 func (v VisitItem) Less(then btree.Item) bool {
-	// VisitedAt as a main index of the tree
-	// TODO; may be bug in deletion if we have visits with the same timestamp
-	return v.VisitedAt.V < then.(VisitItem).VisitedAt.V
+	// VisitedAt as a main index of the tree,
+	// ID keeps visits with the same timestamp apart
+	t := then.(VisitItem)
+	if v.VisitedAt.V != t.VisitedAt.V {
+		return v.VisitedAt.V < t.VisitedAt.V
+	}
+	return v.ID.V < t.ID.V
+}
+
+// dateBound is less than or equal to any visit at visitedAt
+func dateBound(visitedAt int64) VisitItem {
+	return visitKey(visitedAt, math.MinInt32)
 }
 
-func appendIteratorByCountryAndVisit(listPtr *[]model.Visit, country *string, toDistance *int32) func(item btree.Item) bool {
+func appendIteratorByCountryAndVisit(listPtr *[]model.Visit, country *string, toDistance *int32, p *pager) func(item btree.Item) bool {
 	return func(item btree.Item) bool {
 		location := item.(VisitItem).Location
+		// visits of a deleted location have no place to show
+		if location == nil {
+			return true
+		}
 		// country - название страны, в которой находятся интересующие достопримечательности
-		if country != nil && (location == nil || location.Country.V != *country) {
+		if country != nil && location.Country.V != *country {
 			return true
 		}
 		// toDistance - возвращать только те места, у которых расстояние от города меньше этого параметра
-		if toDistance != nil && (location == nil || location.Distance.V >= *toDistance) {
+		if toDistance != nil && location.Distance.V >= *toDistance {
 			return true
 		}
 
-		*listPtr = append(*listPtr, *(item.(VisitItem).Visit))
-		return true
+		ok, stop := p.accept(item.(VisitItem).Visit)
+		if ok {
+			*listPtr = append(*listPtr, *(item.(VisitItem).Visit))
+		}
+		return !stop
 	}
 }
 
@@ -64,40 +83,109 @@ func NewVisitIndex() *VisitIndex {
 	}
 }
 
+// NewLocationVisitIndex creates index that also aggregates the marks of the visits
+func NewLocationVisitIndex() *VisitIndex {
+	return &VisitIndex{
+		byDate: btree.New(4),
+		marks:  newMarkTree(),
+	}
+}
+
 func (vi *VisitIndex) Add(visit *model.Visit) {
 	//vi.mx.Lock()
 	//defer vi.mx.Unlock()
-	vi.byDate.ReplaceOrInsert(VisitItem{visit})
+	replaced := vi.byDate.ReplaceOrInsert(VisitItem{visit})
+	if vi.marks != nil {
+		if replaced != nil {
+			vi.marks.remove(replaced.(VisitItem).Visit)
+		}
+		vi.marks.insert(visit)
+	}
 }
 
-func (vi *VisitIndex) get(fromDate *int64, toDate *int64, iter btree.ItemIterator) {
+// get iterates over visits with fromDate < visited_at < toDate,
+// starting right after the cursor (if any) in the ascending or descending order
+func (vi *VisitIndex) get(fromDate *int64, toDate *int64, after *model.Cursor, desc bool, iter btree.ItemIterator) {
+	if desc {
+		vi.getDesc(fromDate, toDate, after, iter)
+		return
+	}
+	// greaterOrEqual, lessThan
+	var ge, lt *VisitItem
+	if fromDate != nil {
+		b := dateBound(*fromDate + 1)
+		ge = &b
+	}
+	if after != nil {
+		b := afterCursor(after)
+		if ge == nil || ge.Less(b) {
+			ge = &b
+		}
+	}
+	if toDate != nil {
+		b := dateBound(*toDate)
+		lt = &b
+	}
 	switch {
-	case fromDate == nil && toDate == nil:
+	case ge == nil && lt == nil:
 		vi.byDate.Ascend(iter)
-	case fromDate != nil && toDate != nil:
-		greaterOrEqual := VisitItem{&model.Visit{VisitedAt: opt.Int64{V: *fromDate + 1, Defined: true}}}
-		lessThan := VisitItem{&model.Visit{VisitedAt: opt.Int64{V: *toDate, Defined: true}}}
-		vi.byDate.AscendRange(greaterOrEqual, lessThan, iter)
-	case fromDate != nil:
-		greaterOrEqual := VisitItem{&model.Visit{VisitedAt: opt.Int64{V: *fromDate + 1, Defined: true}}}
-		vi.byDate.AscendGreaterOrEqual(greaterOrEqual, iter)
-	case toDate != nil:
-		lessThan := VisitItem{&model.Visit{VisitedAt: opt.Int64{V: *toDate, Defined: true}}}
-		vi.byDate.AscendLessThan(lessThan, iter)
+	case ge != nil && lt != nil:
+		vi.byDate.AscendRange(*ge, *lt, iter)
+	case ge != nil:
+		vi.byDate.AscendGreaterOrEqual(*ge, iter)
+	case lt != nil:
+		vi.byDate.AscendLessThan(*lt, iter)
 	}
 }
 
-func (vi *VisitIndex) GetByCountryAndDistance(fromDate *int64, toDate *int64, country *string, toDistance *int32) model.UserVisitArray {
+func (vi *VisitIndex) getDesc(fromDate *int64, toDate *int64, after *model.Cursor, iter btree.ItemIterator) {
+	// lessOrEqual, greaterThan
+	var le, gt *VisitItem
+	if toDate != nil {
+		b := visitKey(*toDate-1, math.MaxInt32)
+		le = &b
+	}
+	if after != nil {
+		b := beforeCursor(after)
+		if le == nil || b.Less(*le) {
+			le = &b
+		}
+	}
+	if fromDate != nil {
+		b := visitKey(*fromDate, math.MaxInt32)
+		gt = &b
+	}
+	switch {
+	case le == nil && gt == nil:
+		vi.byDate.Descend(iter)
+	case le != nil && gt != nil:
+		vi.byDate.DescendRange(*le, *gt, iter)
+	case le != nil:
+		vi.byDate.DescendLessOrEqual(*le, iter)
+	case gt != nil:
+		vi.byDate.DescendGreaterThan(*gt, iter)
+	}
+}
+
+// GetByCountryAndDistance returns the visits of the page (all the visits if page is nil)
+func (vi *VisitIndex) GetByCountryAndDistance(fromDate *int64, toDate *int64, country *string, toDistance *int32, page *Page) model.UserVisitArray {
 	//vi.mx.RLock()
 	//defer vi.mx.RUnlock()
 	visits := make([]model.Visit, 0)
-	vi.get(fromDate, toDate, appendIteratorByCountryAndVisit(&visits, country, toDistance))
+	p := &pager{page: page}
+	var after *model.Cursor
+	var desc bool
+	if page != nil {
+		after, desc = page.After, page.Desc
+	}
+	vi.get(fromDate, toDate, after, desc, appendIteratorByCountryAndVisit(&visits, country, toDistance, p))
 	userVisits := make([]model.UserVisit, len(visits))
 	for i := range visits {
 		userVisits[i] = model.UserVisitFromVisit(visits[i])
 	}
 	return model.UserVisitArray{
 		Visits: userVisits,
+		Next:   p.next(),
 	}
 }
 
@@ -105,7 +193,7 @@ func (vi *VisitIndex) GetByAgeAndGender(fromDate *int64, toDate *int64, fromAge
 	//vi.mx.RLock()
 	//defer vi.mx.RUnlock()
 	visits := make([]model.Visit, 0)
-	vi.get(fromDate, toDate, appendIteratorByAgeAndGender(&visits, fromAge, toAge, gender))
+	vi.get(fromDate, toDate, nil, false, appendIteratorByAgeAndGender(&visits, fromAge, toAge, gender))
 	return visits
 }
 
@@ -113,9 +201,43 @@ func (vi *VisitIndex) Remove(visit *model.Visit) bool {
 	//vi.mx.Lock()
 	//defer vi.mx.Unlock()
 	deleted := vi.byDate.Delete(VisitItem{visit})
+	if deleted != nil && vi.marks != nil {
+		vi.marks.remove(visit)
+	}
 	return deleted != nil
 }
 
+// MarkSum returns the sum and the number of the marks of the visits with fromDate < visited_at < toDate.
+// Works for the indexes created by NewLocationVisitIndex only.
+func (vi *VisitIndex) MarkSum(fromDate *int64, toDate *int64) (int64, int) {
+	if fromDate != nil && toDate != nil && *fromDate+1 >= *toDate {
+		return 0, 0
+	}
+	sum, count := vi.marks.total()
+	if toDate != nil {
+		sum, count = vi.marks.less(*toDate, math.MinInt32)
+	}
+	if fromDate != nil {
+		s, c := vi.marks.less(*fromDate+1, math.MinInt32)
+		sum, count = sum-s, count-c
+	}
+	return sum, count
+}
+
+// Len returns the number of visits in the index
+func (vi *VisitIndex) Len() int {
+	return vi.byDate.Len()
+}
+
+// All returns all the visits of the index ordered by date
+func (vi *VisitIndex) All() []*model.Visit {
+	visits := make([]*model.Visit, 0, vi.byDate.Len())
+	vi.ApplyToAll(func(visit *model.Visit) {
+		visits = append(visits, visit)
+	})
+	return visits
+}
+
 func (vi *VisitIndex) ApplyToAll(f func(*model.Visit)) {
 	//vi.mx.Lock()
 	//defer vi.mx.Unlock()
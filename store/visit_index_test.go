@@ -1,21 +1,19 @@
 package store
 
 import (
+	"math"
 	"testing"
 
 	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
 )
 
 func TestVisitIndex_ApplyToAll(t *testing.T) {
 	vi := NewVisitIndex()
 	location := &model.Location{}
-	var t1, t2, t3 int64
-	t1 += 1
-	t2 += 2
-	t3 += 3
-	visit1 := model.Visit{VisitedAt: &t1}
-	visit2 := model.Visit{VisitedAt: &t2}
-	visit3 := model.Visit{VisitedAt: &t3}
+	visit1 := model.Visit{ID: opt.OInt32(1), VisitedAt: opt.OInt64(1)}
+	visit2 := model.Visit{ID: opt.OInt32(2), VisitedAt: opt.OInt64(2)}
+	visit3 := model.Visit{ID: opt.OInt32(3), VisitedAt: opt.OInt64(3)}
 	vi.Add(&visit1)
 	vi.Add(&visit2)
 	vi.Add(&visit3)
@@ -28,3 +26,147 @@ func TestVisitIndex_ApplyToAll(t *testing.T) {
 		t.Errorf("visit1.Location: %p, location: %p", visit1.Location, location)
 	}
 }
+
+func TestVisitIndex_SameVisitedAt(t *testing.T) {
+	vi := NewVisitIndex()
+	visits := make([]model.Visit, 100)
+	for i := range visits {
+		visits[i] = model.Visit{ID: opt.OInt32(int32(i)), VisitedAt: opt.OInt64(10)}
+		vi.Add(&visits[i])
+	}
+	if vi.Len() != len(visits) {
+		t.Fatalf("expected %d visits, got %d", len(visits), vi.Len())
+	}
+
+	if !vi.Remove(&visits[42]) {
+		t.Fatal("visit 42 was not removed")
+	}
+	if vi.Remove(&visits[42]) {
+		t.Error("visit 42 was removed twice")
+	}
+	for _, visit := range vi.All() {
+		if visit == &visits[42] {
+			t.Error("wrong visit was removed")
+		}
+	}
+	if vi.Len() != len(visits)-1 {
+		t.Errorf("expected %d visits, got %d", len(visits)-1, vi.Len())
+	}
+}
+
+func TestVisitIndex_SameVisitedAtRange(t *testing.T) {
+	vi := NewVisitIndex()
+	visits := make([]model.Visit, 30)
+	for i := range visits {
+		// 10 visits at each of 9, 10 and 11
+		visits[i] = model.Visit{ID: opt.OInt32(int32(i)), VisitedAt: opt.OInt64(int64(9 + i%3))}
+		vi.Add(&visits[i])
+	}
+
+	var fromDate, toDate int64 = 9, 11
+	cases := []struct {
+		fromDate, toDate *int64
+		expected         int
+	}{
+		{nil, nil, 30},
+		{&fromDate, nil, 20},
+		{nil, &toDate, 20},
+		{&fromDate, &toDate, 10},
+	}
+	for _, c := range cases {
+		got := vi.GetByAgeAndGender(c.fromDate, c.toDate, nil, nil, nil)
+		if len(got) != c.expected {
+			t.Errorf("fromDate: %v, toDate: %v, expected %d visits, got %d", c.fromDate, c.toDate, c.expected, len(got))
+		}
+	}
+}
+
+func TestStore_SameVisitedAtForUserAndLocation(t *testing.T) {
+	s := NewStore()
+	addTestEntities(s, 1)
+
+	const n = 50
+	for i := int32(1); i <= n; i++ {
+		err := s.AddVisit(model.Visit{ID: opt.OInt32(i), UserID: opt.OInt32(1), LocationID: opt.OInt32(1),
+			VisitedAt: opt.OInt64(100), Mark: opt.OUint8(uint8(i % 2 * 5))})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	visits, _ := s.GetVisitsByUserID(1, nil, nil, nil, nil, nil)
+	if len(visits.Visits) != n {
+		t.Errorf("expected %d user visits, got %d", n, len(visits.Visits))
+	}
+	avg, _ := s.GetLocationAvg(1, nil, nil, nil, nil, nil)
+	if math.Abs(avg-2.5) > 1e-9 {
+		t.Errorf("expected 2.5 avg, got %v", avg)
+	}
+
+	// move one of the visits, the rest should stay in place
+	err := s.UpdateVisitByID(7, model.Visit{VisitedAt: opt.OInt64(200)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromDate int64 = 150
+	visits, _ = s.GetVisitsByUserID(1, &fromDate, nil, nil, nil, nil)
+	if len(visits.Visits) != 1 {
+		t.Errorf("expected 1 moved visit, got %d", len(visits.Visits))
+	}
+	visits, _ = s.GetVisitsByUserID(1, nil, nil, nil, nil, nil)
+	if len(visits.Visits) != n {
+		t.Errorf("expected %d user visits after update, got %d", n, len(visits.Visits))
+	}
+	// visit 7 had mark 5, so 24 of the rest 49 visits have mark 5
+	expected := 24.0 * 5 / 49
+	avg, _ = s.GetLocationAvg(1, nil, &fromDate, nil, nil, nil)
+	if math.Abs(avg-expected) > 1e-9 {
+		t.Errorf("expected %v avg, got %v", expected, avg)
+	}
+}
+
+func TestVisitIndex_Page(t *testing.T) {
+	vi := NewVisitIndex()
+	location := &model.Location{Place: opt.OString("p")}
+	visits := make([]model.Visit, 25)
+	for i := range visits {
+		// 5 visits at each of 10, 11, 12, 13 and 14
+		visits[i] = model.Visit{ID: opt.OInt32(int32(i)), VisitedAt: opt.OInt64(int64(10 + i/5)), Mark: opt.OUint8(uint8(i % 5)), Location: location}
+		vi.Add(&visits[i])
+	}
+
+	var fromDate, toDate int64 = 10, 14
+	for _, desc := range []bool{false, true} {
+		page := &Page{Limit: 4, Desc: desc}
+		var seen []model.UserVisit
+		for pages := 0; ; pages++ {
+			if pages > 10 {
+				t.Fatal("too many pages")
+			}
+			result := vi.GetByCountryAndDistance(&fromDate, &toDate, nil, nil, page)
+			seen = append(seen, result.Visits...)
+			if result.Next == nil {
+				break
+			}
+			page.After = result.Next
+		}
+		// visited_at 11, 12 and 13
+		if len(seen) != 15 {
+			t.Fatalf("desc: %v, expected 15 visits, got %d", desc, len(seen))
+		}
+		for i := 1; i < len(seen); i++ {
+			prev, cur := seen[i-1], seen[i]
+			if desc {
+				prev, cur = cur, prev
+			}
+			if prev.VisitedAt > cur.VisitedAt || prev.VisitedAt == cur.VisitedAt && prev.Mark >= cur.Mark {
+				t.Errorf("desc: %v, wrong order at %d: %v, %v", desc, i, seen[i-1], seen[i])
+			}
+		}
+	}
+
+	result := vi.GetByCountryAndDistance(nil, nil, nil, nil, &Page{Offset: 23, Limit: 5})
+	if len(result.Visits) != 2 || result.Next != nil {
+		t.Errorf("expected 2 last visits without next page, got %v", result)
+	}
+}
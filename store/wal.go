@@ -0,0 +1,424 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/la0rg/highloadcup/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// SyncPolicy defines when the WAL file is flushed to the disk
+type SyncPolicy int
+
+const (
+	// SyncAlways calls fsync after every record
+	SyncAlways SyncPolicy = iota
+	// SyncBatch calls fsync periodically in background
+	SyncBatch
+	// SyncNone leaves flushing to the operating system
+	SyncNone
+)
+
+var ErrSyncPolicy = errors.New("Unknown WAL sync policy")
+
+// maxRecordLen protects replay from allocating huge buffers on corrupted length,
+// transactions are written as a single record, so it is bigger than any entity
+const maxRecordLen = 1 << 26
+
+// ParseSyncPolicy converts always|batch|none into SyncPolicy
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch s {
+	case "always":
+		return SyncAlways, nil
+	case "batch":
+		return SyncBatch, nil
+	case "none":
+		return SyncNone, nil
+	}
+	return 0, ErrSyncPolicy
+}
+
+type walOp byte
+
+const (
+	walAddUser walOp = iota + 1
+	walUpdateUser
+	walAddLocation
+	walUpdateLocation
+	walAddVisit
+	walUpdateVisit
+	walDeleteUser
+	walDeleteLocation
+	walDeleteVisit
+	walTx
+)
+
+// walRecord is a single mutation of the store.
+// id is used by updates and deletes only, entities of the add operations contain it themselves.
+// cascade keeps the policy of the delete, so the replay does the same.
+// walTx record keeps the mutations of a transaction in tx, so they are logged (and replayed) together.
+type walRecord struct {
+	op       walOp
+	id       int32
+	cascade  CascadePolicy
+	user     *model.User
+	location *model.Location
+	visit    *model.Visit
+	tx       []*walRecord
+}
+
+// WAL is an append-only log of the store mutations.
+// Record layout: payload length (uint32) | payload | crc32 of payload (uint32), little endian.
+type WAL struct {
+	mx     sync.Mutex
+	f      *os.File
+	buf    bytes.Buffer
+	policy SyncPolicy
+	dirty  bool
+	stop   chan struct{}
+	// size is the length of the complete records, a failed write is cut off at it
+	size int64
+}
+
+// OpenWAL opens (or creates) the log file for appending.
+// interval is used by SyncBatch policy only.
+func OpenWAL(path string, policy SyncPolicy, interval time.Duration) (*WAL, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w := &WAL{
+		f:      f,
+		size:   info.Size(),
+		policy: policy,
+		stop:   make(chan struct{}),
+	}
+	if policy == SyncBatch {
+		go w.syncEvery(interval)
+	}
+	return w, nil
+}
+
+func (w *WAL) syncEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mx.Lock()
+			if w.dirty {
+				if err := w.f.Sync(); err != nil {
+					log.Errorf("WAL sync: %v", err)
+				}
+				w.dirty = false
+			}
+			w.mx.Unlock()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *WAL) append(rec *walRecord) error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	w.buf.Reset()
+	w.buf.Write([]byte{0, 0, 0, 0}) // length placeholder
+	e := newEncoder(&w.buf)
+	encodeRecord(e, rec)
+	if err := e.flush(); err != nil {
+		return err
+	}
+	b := w.buf.Bytes()
+	binary.LittleEndian.PutUint32(b[:4], uint32(len(b)-4))
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(b[4:]))
+	w.buf.Write(sum[:])
+
+	_, err := w.f.Write(w.buf.Bytes())
+	if err == nil && w.policy == SyncAlways {
+		err = w.f.Sync()
+	}
+	if err != nil {
+		// the record is not applied, so it is not replayed either,
+		// and the next records should not follow a partial one
+		if errTruncate := w.f.Truncate(w.size); errTruncate != nil {
+			log.Errorf("WAL truncate: %v", errTruncate)
+		}
+		return err
+	}
+	w.size += int64(w.buf.Len())
+	w.dirty = true
+	return nil
+}
+
+// Sync flushes the appended records to the disk
+func (w *WAL) Sync() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if err := w.f.Sync(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return nil
+}
+
+// truncate drops all the records (they are expected to be in a snapshot already)
+func (w *WAL) truncate() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if err := w.f.Truncate(0); err != nil {
+		return err
+	}
+	w.size = 0
+	return w.f.Sync()
+}
+
+// Close flushes and closes the log file
+func (w *WAL) Close() error {
+	close(w.stop)
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if err := w.f.Sync(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// SetWAL makes the store log every successful mutation to w.
+// It should be called before the store is used concurrently.
+func (s *Store) SetWAL(w *WAL) {
+	s.wal = w
+}
+
+// commit writes the mutation to the WAL (if any) and applies it, so the store and the subscribers
+// see only logged mutations and a failed log changes nothing. The rejected mutations are not logged.
+func (s *Store) commit(rec *walRecord) error {
+	if s.wal != nil {
+		// snapshots stop the commits to truncate the log
+		s.mxCommit.RLock()
+		defer s.mxCommit.RUnlock()
+	}
+	locks := s.lockRecord(rec)
+	defer s.release(&locks)
+	var events *[]Event
+	if s.feed != nil {
+		events = new([]Event)
+	}
+	var err error
+	switch {
+	case s.wal != nil && rec.op == walTx:
+		// the operations of a transaction depend on each other, so it is checked by applying it
+		var undo []*walRecord
+		undo, err = s.applyTx(rec.tx, events)
+		if err == nil {
+			if err = s.wal.append(rec); err != nil {
+				s.revertTx(undo)
+			}
+		}
+	case s.wal != nil:
+		// conflicting mutations share a lock, so they are logged in the order they are applied
+		err = s.check(rec)
+		if err == nil {
+			err = s.wal.append(rec)
+		}
+		if err == nil {
+			err = s.applyRecord(rec, events)
+		}
+	default:
+		err = s.applyRecord(rec, events)
+	}
+	if err != nil {
+		return err
+	}
+	if events != nil && len(*events) > 0 {
+		// events of conflicting mutations are numbered in the order of the mutations too
+		s.feed.publish(*events)
+	}
+	return nil
+}
+
+// check returns the error the mutation fails with without making it,
+// the caller holds the locks of lockRecord
+func (s *Store) check(rec *walRecord) error {
+	switch rec.op {
+	case walAddUser:
+		return s.checkAddUser(rec.user)
+	case walUpdateUser:
+		return s.checkUpdateUser(rec.id, rec.user)
+	case walAddLocation:
+		return s.checkAddLocation(rec.location)
+	case walUpdateLocation:
+		return s.checkUpdateLocation(rec.id, rec.location)
+	case walAddVisit:
+		return s.checkAddVisit(rec.visit)
+	case walUpdateVisit:
+		return s.checkUpdateVisit(rec.id, rec.visit)
+	case walDeleteUser:
+		return s.checkDeleteUser(rec.id, rec.cascade)
+	case walDeleteLocation:
+		return s.checkDeleteLocation(rec.id, rec.cascade)
+	case walDeleteVisit:
+		return s.checkDeleteVisit(rec.id)
+	}
+	return ErrCorrupted
+}
+
+// apply makes the mutation, the caller holds the locks of lockRecord
+func (s *Store) apply(rec *walRecord) error {
+	switch rec.op {
+	case walAddUser:
+		return s.addUser(*rec.user)
+	case walUpdateUser:
+		return s.updateUserByID(rec.id, *rec.user)
+	case walAddLocation:
+		return s.addLocation(*rec.location)
+	case walUpdateLocation:
+		return s.updateLocationByID(rec.id, *rec.location)
+	case walAddVisit:
+		return s.addVisit(*rec.visit)
+	case walUpdateVisit:
+		return s.updateVisitByID(rec.id, *rec.visit)
+	case walDeleteUser:
+		return s.deleteUser(rec.id, rec.cascade)
+	case walDeleteLocation:
+		return s.deleteLocation(rec.id, rec.cascade)
+	case walDeleteVisit:
+		return s.deleteVisit(rec.id)
+	case walTx:
+		_, err := s.applyTx(rec.tx, nil)
+		return err
+	}
+	return ErrCorrupted
+}
+
+// ReplayWAL applies all the records of the log at path to the store and returns their number.
+// A corrupted or incomplete tail is truncated with a warning.
+func (s *Store) ReplayWAL(path string) (int, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	var n int
+	var head [4]byte
+	for {
+		_, err = io.ReadFull(r, head[:])
+		if err == io.EOF {
+			return n, nil
+		}
+		var rec *walRecord
+		var size int64
+		if err == nil {
+			rec, size, err = readRecord(r, head)
+		}
+		if err != nil {
+			log.Warnf("WAL %s is corrupted at offset %d (%v), truncating", path, offset, err)
+			return n, f.Truncate(offset)
+		}
+		// errors are expected for the records that got into the snapshot
+		// right before the process was stopped
+		s.lockAll()
+		s.apply(rec)
+		s.unlockAll()
+		offset += size
+		n++
+	}
+}
+
+func readRecord(r io.Reader, head [4]byte) (*walRecord, int64, error) {
+	l := binary.LittleEndian.Uint32(head[:])
+	if l > maxRecordLen {
+		return nil, 0, ErrCorrupted
+	}
+	b := make([]byte, l+4)
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(b[:l]) != binary.LittleEndian.Uint32(b[l:]) {
+		return nil, 0, ErrCorrupted
+	}
+
+	d := newDecoder(bytes.NewReader(b[:l]))
+	rec := decodeRecord(d, false)
+	if d.err != nil {
+		return nil, 0, d.err
+	}
+	return rec, int64(len(head) + len(b)), nil
+}
+
+func encodeRecord(e *encoder, rec *walRecord) {
+	e.byte(byte(rec.op))
+	e.varint(int64(rec.id))
+	switch {
+	case rec.op == walTx:
+		e.varint(int64(len(rec.tx)))
+		for _, r := range rec.tx {
+			encodeRecord(e, r)
+		}
+	case rec.user != nil:
+		e.user(rec.user)
+	case rec.location != nil:
+		e.location(rec.location)
+	case rec.visit != nil:
+		e.visit(rec.visit)
+	default:
+		e.byte(byte(rec.cascade))
+	}
+}
+
+// decodeRecord reads a record written by encodeRecord, transactions can't be nested
+func decodeRecord(d *decoder, nested bool) *walRecord {
+	rec := &walRecord{op: walOp(d.byte())}
+	rec.id = int32(d.varint())
+	switch rec.op {
+	case walAddUser, walUpdateUser:
+		rec.user = &model.User{}
+		d.user(rec.user)
+	case walAddLocation, walUpdateLocation:
+		rec.location = &model.Location{}
+		d.location(rec.location)
+	case walAddVisit, walUpdateVisit:
+		rec.visit = &model.Visit{}
+		d.visit(rec.visit)
+	case walDeleteUser, walDeleteLocation, walDeleteVisit:
+		rec.cascade = CascadePolicy(d.byte())
+	case walTx:
+		n := d.varint()
+		// every record takes at least 2 bytes
+		if nested || n < 0 || n > maxRecordLen/2 {
+			d.fail(ErrCorrupted)
+			return nil
+		}
+		rec.tx = make([]*walRecord, 0, n)
+		for i := int64(0); i < n && d.err == nil; i++ {
+			rec.tx = append(rec.tx, decodeRecord(d, true))
+		}
+	default:
+		d.fail(ErrCorrupted)
+		return nil
+	}
+	return rec
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/mailru/easyjson/opt"
+)
+
+// walTestLog logs the mutations of newTxTestStore to a new file and returns the file
+// and the offsets of the record ends
+func walTestLog(t *testing.T, dir string) (string, []int64) {
+	path := filepath.Join(dir, "wal")
+	s := newTxTestStore()
+	wal, err := OpenWAL(path, SyncAlways, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetWAL(wal)
+	s.SetCascadePolicy(CascadeDelete)
+
+	var ends []int64
+	logged := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ends = append(ends, info.Size())
+	}
+	logged(s.AddUser(testUser(3)))
+	logged(s.UpdateLocationByID(1, model.Location{City: opt.OString("x")}))
+	tx := s.Begin()
+	tx.UpdateVisitByID(1, model.Visit{UserID: opt.OInt32(3), LocationID: opt.OInt32(2)})
+	tx.UpdateUserByID(3, model.User{FirstName: opt.OString("x")})
+	logged(tx.Commit())
+	logged(s.DeleteUser(1))
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path, ends
+}
+
+func TestWAL_Replay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path, _ := walTestLog(t, dir)
+
+	// the restarted process starts from the same data
+	s := newTxTestStore()
+	n, err := s.ReplayWAL(path)
+	if err != nil || n != 4 {
+		t.Fatalf("expected 4 records, got %d, %v", n, err)
+	}
+	if u, ok := s.GetUserByID(3); !ok || u.FirstName.V != "x" {
+		t.Errorf("expected user 3 updated by the transaction, got %v", u)
+	}
+	if l, _ := s.GetLocationByID(1); l.City.V != "x" {
+		t.Errorf("expected the updated city, got %v", l)
+	}
+	if v, ok := s.GetVisitByID(1); !ok || v.UserID.V != 3 || v.LocationID.V != 2 {
+		t.Errorf("expected visit 1 moved by the transaction, got %v", v)
+	}
+	// the policy of the record is replayed, not the one of the store
+	if _, ok := s.GetUserByID(1); ok {
+		t.Error("deleted user is replayed")
+	}
+	if _, ok := s.GetVisitByID(2); ok {
+		t.Error("visit of the deleted user is kept")
+	}
+}
+
+func TestWAL_TruncatesTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path, ends := walTestLog(t, dir)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := ends[len(ends)-2]
+
+	badSum := append([]byte(nil), data...)
+	badSum[len(badSum)-1] ^= 0xff
+	hugeLen := append(append([]byte(nil), data...), 0xff, 0xff, 0xff, 0xff, 1)
+	cases := []struct {
+		name    string
+		data    []byte
+		records int
+		size    int64
+	}{
+		{"complete", data, 4, int64(len(data))},
+		{"short head", append(append([]byte(nil), data...), 1, 0), 4, int64(len(data))},
+		{"short record", data[:len(data)-2], 3, last},
+		{"bad checksum", badSum, 3, last},
+		{"huge length", hugeLen, 4, int64(len(data))},
+		{"transaction cut", data[:ends[1]+5], 2, ends[1]},
+	}
+	for _, c := range cases {
+		if err := ioutil.WriteFile(path, c.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		n, err := newTxTestStore().ReplayWAL(path)
+		if err != nil || n != c.records {
+			t.Errorf("%s: expected %d records, got %d, %v", c.name, c.records, n, err)
+		}
+		if info, err := os.Stat(path); err != nil || info.Size() != c.size {
+			t.Errorf("%s: expected the log truncated at %d, got %v, %v", c.name, c.size, info.Size(), err)
+		}
+	}
+}
+
+func TestWAL_FailedAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := newTxTestStore()
+	s.EnableEvents(8)
+	wal, err := OpenWAL(filepath.Join(dir, "wal"), SyncNone, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetWAL(wal)
+	wal.f.Close()
+
+	if err := s.UpdateUserByID(1, model.User{FirstName: opt.OString("x")}); err == nil {
+		t.Fatal("update is not logged but succeeded")
+	}
+	if u, _ := s.GetUserByID(1); u.FirstName.V != "a" {
+		t.Errorf("update that is not logged is applied: %v", u)
+	}
+	if errs := s.Batch([]Op{{Update: true, ID: 1, Visit: &model.Visit{Mark: opt.OUint8(5)}}}, true); errs[0] == nil {
+		t.Error("batch is not logged but succeeded")
+	}
+	if v, _ := s.GetVisitByID(1); v.Mark.V != 2 {
+		t.Errorf("batch that is not logged is applied: %v", v)
+	}
+	if seq := s.LastEventSeq(); seq != 0 {
+		t.Errorf("events of the mutations that are not logged are published: %d", seq)
+	}
+}
+
+func TestWAL_RejectedMutations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "wal")
+	s := newTxTestStore()
+	wal, err := OpenWAL(path, SyncAlways, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetWAL(wal)
+
+	if err := s.UpdateUserByID(3, model.User{FirstName: opt.OString("x")}); err != ErrDoesNotExist {
+		t.Errorf("expected ErrDoesNotExist, got %v", err)
+	}
+	if err := s.UpdateUserByID(1, model.User{Gender: opt.OString("x")}); err == nil {
+		t.Error("invalid update succeeded")
+	}
+	if err := s.AddUser(testUser(1)); err == nil {
+		t.Error("add of an existing user succeeded")
+	}
+	if err := s.DeleteUser(1); err != ErrHasVisits {
+		t.Errorf("expected ErrHasVisits, got %v", err)
+	}
+	if err := s.DeleteVisit(11); err != ErrDoesNotExist {
+		t.Errorf("expected ErrDoesNotExist, got %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("rejected mutations are logged: %d bytes", info.Size())
+	}
+}
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"archive/zip"
+	"bufio"
+	"io"
+	"strconv"
+
+	"github.com/la0rg/highloadcup/store"
+	"github.com/mailru/easyjson"
+)
+
+// ExportToZip writes the store as the zip archive imported by ImportData:
+// {"users": [...]} in users_1.json, users_2.json, ... with up to pageSize records in every file,
+// and the same for the locations and the visits (pageSize <= 0 writes a single file per entity)
+func ExportToZip(w io.Writer, s *store.Store, pageSize int) error {
+	zw := zip.NewWriter(w)
+	p := &pageWriter{zip: zw, pageSize: pageSize}
+	err := s.Export(p.write)
+	if err == nil {
+		// every entity has a file, an empty one too
+		for _, entity := range []string{store.EntityUsers, store.EntityLocations, store.EntityVisits} {
+			if p.pages[entity] == 0 {
+				if err = p.open(entity); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if err == nil {
+		err = p.close()
+	}
+	if err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// pageWriter splits the exported entities into the files of the archive
+type pageWriter struct {
+	zip      *zip.Writer
+	pageSize int
+	pages    map[string]int
+
+	// the open file
+	w      *bufio.Writer
+	entity string
+	count  int
+}
+
+func (p *pageWriter) write(entity string, v easyjson.Marshaler) error {
+	if p.w == nil || entity != p.entity || (p.pageSize > 0 && p.count >= p.pageSize) {
+		if err := p.open(entity); err != nil {
+			return err
+		}
+	}
+	if p.count > 0 {
+		p.w.WriteString(", ")
+	}
+	p.count++
+	_, err := easyjson.MarshalToWriter(v, p.w)
+	return err
+}
+
+// open closes the open file and starts the next page of entity
+func (p *pageWriter) open(entity string) error {
+	if err := p.close(); err != nil {
+		return err
+	}
+	if p.pages == nil {
+		p.pages = make(map[string]int)
+	}
+	p.pages[entity]++
+	fw, err := p.zip.Create(entity + "_" + strconv.Itoa(p.pages[entity]) + ".json")
+	if err != nil {
+		return err
+	}
+	p.w, p.entity, p.count = bufio.NewWriter(fw), entity, 0
+	_, err = p.w.WriteString(`{"` + entity + `": [`)
+	return err
+}
+
+func (p *pageWriter) close() error {
+	if p.w == nil {
+		return nil
+	}
+	p.w.WriteString("]}")
+	err := p.w.Flush()
+	p.w = nil
+	return err
+}
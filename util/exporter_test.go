@@ -0,0 +1,76 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/la0rg/highloadcup/store"
+)
+
+func TestExportToZip(t *testing.T) {
+	path := writeZip(t, testFiles)
+	defer os.Remove(path)
+	s := store.NewStore()
+	if _, err := ImportData(path, ImportOptions{Workers: 1}, s); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := ExportToZip(&b, s, 2); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	expected := []string{"users_1.json", "locations_1.json", "visits_1.json", "visits_2.json"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected files %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected files %v, got %v", expected, names)
+		}
+	}
+	rc, _ := r.File[3].Open()
+	visits, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	if string(visits) != `{"visits": [{"id":3,"location":1,"user":2,"visited_at":300,"mark":1}]}` {
+		t.Errorf("unexpected visits_2.json %s", visits)
+	}
+
+	// the exported archive seeds the same store
+	exported := tempPath(t, ".zip")
+	if err := ioutil.WriteFile(exported, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(exported)
+	seeded := store.NewStore()
+	if _, err := ImportData(exported, ImportOptions{Workers: 4}, seeded); err != nil {
+		t.Fatal(err)
+	}
+	checkLinks(t, seeded, "exported")
+	var again bytes.Buffer
+	if err := ExportToZip(&again, seeded, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), again.Bytes()) {
+		t.Error("export of the imported export differs")
+	}
+
+	var empty bytes.Buffer
+	if err := ExportToZip(&empty, store.NewStore(), 0); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = zip.NewReader(bytes.NewReader(empty.Bytes()), int64(empty.Len()))
+	if len(r.File) != 3 {
+		t.Errorf("expected empty files of all entities, got %d files", len(r.File))
+	}
+}
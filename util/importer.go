@@ -1,126 +1,356 @@
 package util
 
 import (
-	"archive/zip"
-	"bufio"
-	"encoding/json"
-	"errors"
-	"io/ioutil"
-	"os"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/la0rg/highloadcup/model"
 	"github.com/la0rg/highloadcup/store"
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/opt"
 	log "github.com/sirupsen/logrus"
 )
 
-var errUnsupportedFile = errors.New("Imported file is not supported")
+// progressInterval is the interval of the import progress logs
+const progressInterval = time.Second
 
-func ImportDataFromZip(store *store.Store) error {
-	r, err := zip.OpenReader("/tmp/data/data.zip")
+// reasons of the rejected records in the import report
+const (
+	reasonInvalidJSON     = "invalid_json"
+	reasonMissingField    = "missing_field"
+	reasonInvalidField    = "invalid_field"
+	reasonInvalidID       = "invalid_id"
+	reasonDuplicateID     = "duplicate_id"
+	reasonUnknownUser     = "unknown_user"
+	reasonUnknownLocation = "unknown_location"
+	reasonOther           = "other"
+)
+
+// ImportOptions select the source and the format of the imported data
+type ImportOptions struct {
+	// Source is SourceDir, SourceZip, SourceTar or empty to detect it by the path
+	Source string
+	// Format is FormatJSON, FormatNDJSON or empty to detect it by the extension of every file
+	Format string
+	// Workers is the number of the files imported concurrently, GOMAXPROCS if it is not positive
+	Workers int
+	// Merge makes the records of the existing ids update them instead of being rejected
+	Merge bool
+}
+
+// ImportData adds the users, locations and visits of the data files at path to the store
+// and reports the accepted and rejected records of every file.
+// The entity of a file is the part of its name before "_" or ".": users_1.json, visits.ndjson.gz.
+// Files of the unknown entities or formats are skipped with a warning.
+// The files are streamed, the memory used does not depend on their size.
+// The visits of the users and locations missing from the data are rejected after all the files are read.
+func ImportData(path string, opts ImportOptions, store *store.Store) (*model.ImportReport, error) {
+	switch opts.Format {
+	case "", FormatJSON, FormatNDJSON:
+	default:
+		return nil, ErrUnknownFormat
+	}
+	src, err := OpenSource(path, opts.Source)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer r.Close()
+	defer src.Close()
 
-	for _, f := range r.File {
-		name := f.Name
+	imp := newImporter(store, opts)
+	err = src.Walk(opts.Workers, imp.importFile)
+	if err != nil {
+		imp.progress.stop()
+		return nil, err
+	}
+	imp.rejectDangling()
+	imp.progress.stop()
+	// the files are imported concurrently, so they are reported in the order of their names
+	sort.Slice(imp.files, func(i, j int) bool { return imp.files[i].Name < imp.files[j].Name })
+	sort.Strings(imp.skipped)
+	report := &model.ImportReport{Source: path, Skipped: imp.skipped}
+	for _, f := range imp.files {
+		report.Files = append(report.Files, *f)
+		report.Accepted += f.Accepted
+		report.Updated += f.Updated
+		report.Rejected += f.Rejected
+	}
+	return report, nil
+}
 
-		// cut off folder part
-		i := strings.LastIndex(name, "/")
-		if i != -1 {
-			name = name[i:]
+// LogImportReport writes the totals of the report and the rejections of every file
+func LogImportReport(report *model.ImportReport) {
+	log.Infof("Imported %s: %d records accepted (%d updates), %d rejected", report.Source, report.Accepted, report.Updated, report.Rejected)
+	for _, f := range report.Files {
+		if f.Rejected == 0 {
+			continue
 		}
-		// cut off extension part
-		i = strings.LastIndex(name, ".")
-		if i != -1 {
-			name = name[:i]
+		reasons := make([]string, 0, len(f.Reasons))
+		for reason, n := range f.Reasons {
+			reasons = append(reasons, reason+": "+strconv.FormatInt(n, 10))
 		}
+		sort.Strings(reasons)
+		log.Warnf("Rejected %d of %d records of %s (%s)", f.Rejected, f.Accepted+f.Rejected, f.Name, strings.Join(reasons, ", "))
+	}
+}
 
-		parts := strings.Split(name, "_")
-		if len(parts) != 2 {
-			return errUnsupportedFile
-		}
+// fileEntity returns the entity of the file by its name without the directories,
+// the part before "_" or "." (users_1.json, visits.ndjson), it is empty for the unknown entities
+func fileEntity(name string) string {
+	name = path.Base(name)
+	if i := strings.IndexAny(name, "_."); i >= 0 {
+		name = name[:i]
+	}
+	switch name {
+	case store.EntityUsers, store.EntityLocations, store.EntityVisits:
+		return name
+	}
+	return ""
+}
 
-		log.Infof("Start reading file %s", name)
-		// add concurrent processing
-		rc, err := f.Open()
-		bytes, err := ioutil.ReadAll(rc)
-		if err != nil {
-			return err
-		}
-		err = rc.Close()
-		if err != nil {
-			return err
-		}
+// importer adds the records of the files to the store
+type importer struct {
+	store    *store.Store
+	format   string
+	merge    bool
+	progress *progress
 
-		switch parts[0] {
-		case "users":
-			err = importUsers(bytes, store)
-		case "locations":
-			err = importLocations(bytes, store)
-		case "visits":
-			err = importVisits(bytes, store)
-		}
+	mx sync.Mutex
+	// files are the reports of the imported files
+	files   []*model.ImportFileReport
+	skipped []string
+	// pending are the visits added before their user or location
+	pending []pendingVisit
+}
+
+type pendingVisit struct {
+	id, user, location int32
+	file               *model.ImportFileReport
+}
+
+func newImporter(store *store.Store, opts ImportOptions) *importer {
+	return &importer{store: store, format: opts.Format, merge: opts.Merge, progress: newProgress()}
+}
+
+// importFile imports the records of the file, the files of the unknown entities or formats are skipped
+func (imp *importer) importFile(name string, r io.Reader) error {
+	entity := fileEntity(name)
+	format, gzipped := fileFormat(name)
+	if imp.format != "" {
+		format = imp.format
+	}
+	if entity == "" || format == "" {
+		log.Warnf("Skipped %s: unknown entity or format", name)
+		imp.mx.Lock()
+		imp.skipped = append(imp.skipped, name)
+		imp.mx.Unlock()
+		return nil
+	}
+
+	log.Infof("Start reading file %s", name)
+	if gzipped {
+		gz, err := gzip.NewReader(r)
 		if err != nil {
-			return err
+			return fmt.Errorf("%s: %v", name, err)
 		}
+		defer gz.Close()
+		r = gz
 	}
-	return nil
-}
-
-func importUsers(b []byte, store *store.Store) error {
-	var users model.UserArray
-	err := json.Unmarshal(b, &users)
+	d, err := newRecordDecoder(format, r, entity)
 	if err != nil {
 		return err
 	}
-	for _, u := range users.Users {
-		store.AddUser(u)
+	file := &model.ImportFileReport{Name: name, Entity: entity, Reasons: make(map[string]int64)}
+	imp.mx.Lock()
+	imp.files = append(imp.files, file)
+	imp.mx.Unlock()
+	err = imp.importRecords(entity, d, file)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
 	}
 	return nil
 }
 
-func importLocations(b []byte, store *store.Store) error {
-	var locations model.LocationArray
-	err := json.Unmarshal(b, &locations)
-	if err != nil {
-		return err
+// importRecords decodes and adds the records one by one.
+// Records that are not added are counted in file, errors of the file format stop the import.
+func (imp *importer) importRecords(entity string, d recordDecoder, file *model.ImportFileReport) error {
+	s := imp.store
+	// add returns true if the record updated the existing one,
+	// in the merge mode the records of the existing ids are updates and may skip the required fields
+	var add func([]byte) (bool, error)
+	var count *int64
+	switch entity {
+	case "users":
+		add, count = func(b []byte) (bool, error) {
+			var user model.User
+			if err := easyjson.Unmarshal(b, &user); err != nil {
+				return false, err
+			}
+			if imp.merge && user.ID.Defined {
+				if _, ok := s.GetUserByID(user.ID.V); ok {
+					id := user.ID.V
+					user.ID = opt.Int32{}
+					return true, s.UpdateUserByID(id, user)
+				}
+			}
+			return false, s.AddUser(user)
+		}, &imp.progress.users
+	case "locations":
+		add, count = func(b []byte) (bool, error) {
+			var location model.Location
+			if err := easyjson.Unmarshal(b, &location); err != nil {
+				return false, err
+			}
+			if imp.merge && location.ID.Defined {
+				if _, ok := s.GetLocationByID(location.ID.V); ok {
+					id := location.ID.V
+					location.ID = opt.Int32{}
+					return true, s.UpdateLocationByID(id, location)
+				}
+			}
+			return false, s.AddLocation(location)
+		}, &imp.progress.locations
+	case "visits":
+		add, count = func(b []byte) (bool, error) {
+			var visit model.Visit
+			if err := easyjson.Unmarshal(b, &visit); err != nil {
+				return false, err
+			}
+			if imp.merge && visit.ID.Defined {
+				if _, ok := s.GetVisitByID(visit.ID.V); ok {
+					id := visit.ID.V
+					visit.ID = opt.Int32{}
+					return true, s.UpdateVisitByID(id, visit)
+				}
+			}
+			err := s.AddVisit(visit)
+			if err == nil {
+				imp.checkLinks(&visit, file)
+			}
+			return false, err
+		}, &imp.progress.visits
 	}
-	for _, l := range locations.Locations {
-		store.AddLocation(l)
+
+	for {
+		b, err := d.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		updated, err := add(b)
+		switch {
+		case err != nil:
+			reject(file, rejectReason(err))
+		case updated:
+			file.Accepted++
+			file.Updated++
+		default:
+			file.Accepted++
+		}
+		atomic.AddInt64(count, 1)
 	}
-	return nil
 }
 
-func importVisits(b []byte, store *store.Store) error {
-	var visits model.VisitArray
-	err := json.Unmarshal(b, &visits)
-	if err != nil {
-		return err
+// checkLinks keeps the visit for rejectDangling if its user or location is not added yet
+func (imp *importer) checkLinks(visit *model.Visit, file *model.ImportFileReport) {
+	_, user := imp.store.GetUserByID(visit.UserID.V)
+	_, location := imp.store.GetLocationByID(visit.LocationID.V)
+	if user && location {
+		return
 	}
-	for _, v := range visits.Visits {
-		store.AddVisit(v)
-	}
-	return nil
+	imp.mx.Lock()
+	imp.pending = append(imp.pending, pendingVisit{id: visit.ID.V, user: visit.UserID.V, location: visit.LocationID.V, file: file})
+	imp.mx.Unlock()
 }
 
-func ImportCurrentTimestamp() time.Time {
-	file, err := os.Open("/tmp/data/options.txt")
-	defer file.Close()
-	if err != nil {
-		log.Fatal(err)
+// rejectDangling deletes the visits of the users and locations that are not imported
+func (imp *importer) rejectDangling() {
+	for _, v := range imp.pending {
+		reason := ""
+		if _, ok := imp.store.GetUserByID(v.user); !ok {
+			reason = reasonUnknownUser
+		} else if _, ok := imp.store.GetLocationByID(v.location); !ok {
+			reason = reasonUnknownLocation
+		}
+		if reason == "" {
+			continue
+		}
+		if err := imp.store.DeleteVisit(v.id); err != nil {
+			log.Errorf("Could not delete dangling visit %d: %v", v.id, err)
+			continue
+		}
+		v.file.Accepted--
+		reject(v.file, reason)
 	}
-	scan := bufio.NewScanner(file)
-	if !scan.Scan() {
-		log.Fatal("Could not scan the option.txt file.")
+	imp.pending = nil
+}
+
+func reject(file *model.ImportFileReport, reason string) {
+	file.Rejected++
+	file.Reasons[reason]++
+}
+
+func rejectReason(err error) string {
+	switch e := err.(type) {
+	case model.FieldErrors:
+		if e[0].Err == store.ErrRequiredFields {
+			return reasonMissingField
+		}
+		return reasonInvalidField
+	case *jlexer.LexerError:
+		return reasonInvalidJSON
 	}
-	timestampText := scan.Text()
-	i64, err := strconv.ParseInt(timestampText, 10, 64)
-	if err != nil {
-		log.Fatal(err)
+	switch err {
+	case store.ErrInvalidID:
+		return reasonInvalidID
+	case store.ErrAlreadyExist:
+		return reasonDuplicateID
 	}
-	return time.Unix(i64, 0)
+	return reasonOther
+}
+
+// progress counts the imported records and logs them periodically
+type progress struct {
+	users, locations, visits int64
+	start                    time.Time
+	done                     chan struct{}
+}
+
+func newProgress() *progress {
+	p := &progress{start: time.Now(), done: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.log("Importing")
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *progress) log(state string) {
+	users, locations, visits := atomic.LoadInt64(&p.users), atomic.LoadInt64(&p.locations), atomic.LoadInt64(&p.visits)
+	elapsed := time.Since(p.start)
+	log.Infof("%s: %d users, %d locations, %d visits in %v (%.0f records/s)", state, users, locations, visits,
+		elapsed, float64(users+locations+visits)/elapsed.Seconds())
+}
+
+func (p *progress) stop() {
+	close(p.done)
+	p.log("Imported")
 }
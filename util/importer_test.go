@@ -0,0 +1,187 @@
+package util
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/la0rg/highloadcup/store"
+)
+
+func TestArrayDecoder(t *testing.T) {
+	cases := []struct {
+		data     string
+		elements []string
+		err      bool
+	}{
+		{`{"users": []}`, nil, false},
+		{` { "users" : [ {"id": 1} , {"a": "}\"{", "b": [1, {}]},{}] } `, []string{`{"id": 1}`, `{"a": "}\"{", "b": [1, {}]}`, `{}`}, false},
+		{`{"users":[1,"x"]}`, []string{`1`, `"x"`}, false},
+		{`{"visits": []}`, nil, true},
+		{`{"users": [{"id": 1}`, nil, true},
+		{`{"users": [{"id": 1},]}`, []string{`{"id": 1}`}, true},
+		{`{"users": [{"id": 1}}]}`, nil, true},
+		{`["users"]`, nil, true},
+	}
+	for _, c := range cases {
+		d := newArrayDecoder(strings.NewReader(c.data), "users")
+		var elements []string
+		var err error
+		for {
+			var b []byte
+			b, err = d.Next()
+			if err != nil {
+				break
+			}
+			elements = append(elements, string(b))
+		}
+		if (err != io.EOF) != c.err {
+			t.Errorf("%s: expected error %v, got %v", c.data, c.err, err)
+		}
+		if strings.Join(elements, "|") != strings.Join(c.elements, "|") {
+			t.Errorf("%s: expected %q, got %q", c.data, c.elements, elements)
+		}
+	}
+}
+
+// tempPath returns a new temporary file name with the extension
+func tempPath(t *testing.T, ext string) string {
+	f, err := ioutil.TempFile("", "hlc-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return f.Name() + ext
+}
+
+func writeZip(t *testing.T, files [][2]string) string {
+	f, err := os.Create(tempPath(t, ".zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, file := range files {
+		fw, err := w.Create(file[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(file[1]))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+var testFiles = [][2]string{
+	{"data/visits_1.json", `{"visits": [{"id": 1, "location": 1, "user": 1, "visited_at": 100, "mark": 5},
+		{"id": 2, "location": 2, "user": 1, "visited_at": 200, "mark": 3}]}`},
+	{"data/visits_2.json", `{"visits": [{"id": 3, "location": 1, "user": 2, "visited_at": 300, "mark": 1}]}`},
+	{"data/locations_1.json", `{"locations": [{"id": 1, "place": "p", "country": "c", "city": "a", "distance": 1},
+		{"id": 2, "place": "q", "country": "d", "city": "b", "distance": 2}]}`},
+	{"data/users_1.json", `{"users": [{"id": 1, "email": "a@b.c", "first_name": "a", "last_name": "b", "gender": "m", "birth_date": 0},
+		{"id": 2, "email": "b@b.c", "first_name": "a", "last_name": "b", "gender": "f", "birth_date": 0}]}`},
+}
+
+func checkLinks(t *testing.T, s *store.Store, name string) {
+	visits, ok := s.GetVisitsByUserID(1, nil, nil, nil, nil, nil)
+	if !ok || len(visits.Visits) != 2 || visits.Visits[0].Place != "p" || visits.Visits[1].Place != "q" {
+		t.Errorf("%s: expected the visits of user 1 with places, got %+v", name, visits)
+	}
+	gender := "f"
+	if avg, ok := s.GetLocationAvg(1, nil, nil, nil, nil, &gender); !ok || avg < 1 || avg > 1.0001 {
+		t.Errorf("%s: expected avg 1 of user 2, got %v", name, avg)
+	}
+}
+
+func TestImportData(t *testing.T) {
+	path := writeZip(t, testFiles)
+	defer os.Remove(path)
+	for _, workers := range []int{1, 4} {
+		s := store.NewStore()
+		report, err := ImportData(path, ImportOptions{Workers: workers}, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Accepted != 7 || report.Rejected != 0 || len(report.Files) != 4 {
+			t.Errorf("%d workers: unexpected report %+v", workers, report)
+		}
+		for i, name := range []string{"data/locations_1.json", "data/users_1.json", "data/visits_1.json", "data/visits_2.json"} {
+			if i < len(report.Files) && report.Files[i].Name != name {
+				t.Errorf("%d workers: expected file %d %s, got %s", workers, i, name, report.Files[i].Name)
+			}
+		}
+		checkLinks(t, s, path)
+	}
+
+	// the visits are linked to the users and locations imported after them
+	s := store.NewStore()
+	imp := newImporter(s, ImportOptions{})
+	for _, f := range testFiles {
+		if err := imp.importFile(f[0], strings.NewReader(f[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	imp.rejectDangling()
+	p := imp.progress
+	p.stop()
+	checkLinks(t, s, "visits first")
+	if p.users != 2 || p.locations != 2 || p.visits != 3 {
+		t.Errorf("expected 2 users, 2 locations and 3 visits, got %+v", p)
+	}
+
+	bad := writeZip(t, [][2]string{{"users_1.json", `{"users": [{"id": 1]}`}})
+	defer os.Remove(bad)
+	if _, err := ImportData(bad, ImportOptions{}, store.NewStore()); err == nil || !strings.Contains(err.Error(), "users_1.json") {
+		t.Errorf("expected the error of users_1.json, got %v", err)
+	}
+}
+
+func TestImportData_Report(t *testing.T) {
+	path := writeZip(t, [][2]string{
+		{"visits_1.json", `{"visits": [{"id": 1, "location": 1, "user": 1, "visited_at": 100, "mark": 5},
+			{"id": 2, "location": 1, "user": 2, "visited_at": 100, "mark": 5},
+			{"id": 3, "location": 2, "user": 1, "visited_at": 100, "mark": 5},
+			{"id": 1, "location": 1, "user": 1, "visited_at": 200, "mark": 1},
+			{"id": 4, "location": 1, "user": 1, "visited_at": 100, "mark": 9}]}`},
+		{"users_1.json", `{"users": [{"id": 1, "email": "a@b.c", "first_name": "a", "last_name": "b", "gender": "m", "birth_date": 0},
+			{"id": -2, "email": "b@b.c", "first_name": "a", "last_name": "b", "gender": "f", "birth_date": 0},
+			{"id": 3, "email": "b@b.c", "first_name": "a", "last_name": "b", "gender": "f"},
+			{"id": "x"}]}`},
+		{"locations_1.json", `{"locations": [{"id": 1, "place": "p", "country": "c", "city": "a", "distance": 1}]}`},
+	})
+	defer os.Remove(path)
+	s := store.NewStore()
+	report, err := ImportData(path, ImportOptions{Workers: 2}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]model.ImportFileReport{
+		"users_1.json":     {Entity: "users", Accepted: 1, Rejected: 3, Reasons: map[string]int64{reasonInvalidID: 1, reasonMissingField: 1, reasonInvalidJSON: 1}},
+		"locations_1.json": {Entity: "locations", Accepted: 1, Reasons: map[string]int64{}},
+		"visits_1.json":    {Entity: "visits", Accepted: 1, Rejected: 4, Reasons: map[string]int64{reasonUnknownUser: 1, reasonUnknownLocation: 1, reasonDuplicateID: 1, reasonInvalidField: 1}},
+	}
+	for _, f := range report.Files {
+		e := expected[f.Name]
+		e.Name = f.Name
+		if !reflect.DeepEqual(f, e) {
+			t.Errorf("expected %+v, got %+v", e, f)
+		}
+	}
+	if report.Accepted != 3 || report.Rejected != 7 || report.Source != path {
+		t.Errorf("unexpected totals %+v", report)
+	}
+	if _, ok := s.GetVisitByID(2); ok {
+		t.Error("visit of the unknown user is kept")
+	}
+	if visits, _ := s.GetVisitsByUserID(1, nil, nil, nil, nil, nil); len(visits.Visits) != 1 {
+		t.Errorf("expected the only visit of user 1, got %+v", visits)
+	}
+}
@@ -1,18 +1,19 @@
 package util
 
-import (
-	"strings"
-	"unicode"
-)
+import "unicode"
 
+// OnlyLetters checks that s has letters and consists of letters, spaces and hyphens,
+// so the multi-word names like "Соединённые Штаты" or "Гвинея-Бисау" match
 func OnlyLetters(s string) bool {
-	return unicode.IsLetter(rune(s[0]))
-}
-
-func IsGender(s string) bool {
-	return s == "m" || s == "f"
-}
-
-func ContainsNull(bytes []byte) bool {
-	return strings.Contains(string(bytes), ": null")
+	letters := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+			letters = true
+		case r == ' ' || r == '-':
+		default:
+			return false
+		}
+	}
+	return letters
 }
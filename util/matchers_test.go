@@ -0,0 +1,26 @@
+package util
+
+import "testing"
+
+func TestOnlyLetters(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected bool
+	}{
+		{"Россия", true},
+		{"Russia", true},
+		{"Соединённые Штаты", true},
+		{"Гвинея-Бисау", true},
+		{"", false},
+		{" ", false},
+		{"-", false},
+		{"123", false},
+		{"Россия1", false},
+		{"Россия,", false},
+	}
+	for _, test := range tests {
+		if got := OnlyLetters(test.s); got != test.expected {
+			t.Errorf("OnlyLetters(%q): expected %v, got %v", test.s, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/la0rg/highloadcup/store"
+)
+
+var ErrUnknownSource = errors.New("Data source should be dir, zip, tar or file")
+
+// Kinds of the data sources
+const (
+	SourceDir = "dir"
+	SourceZip = "zip"
+	// SourceTar is a tar archive, gzipped or not
+	SourceTar = "tar"
+	// SourceFile is a single data file
+	SourceFile = "file"
+)
+
+// Source is a set of the data files
+type Source interface {
+	// Walk calls f for every file of the source, r is valid only during the call.
+	// Up to workers calls run concurrently (GOMAXPROCS if workers <= 0) if the source has random access to the files,
+	// the users and locations files are passed before the visits files then.
+	Walk(workers int, f func(name string, r io.Reader) error) error
+	Close() error
+}
+
+// OpenSource opens the directory or the archive at path,
+// kind is SourceDir, SourceZip, SourceTar, SourceFile or empty to detect it by the path
+func OpenSource(path, kind string) (Source, error) {
+	if kind == "" {
+		kind = sourceKind(path)
+	}
+	switch kind {
+	case SourceDir:
+		return dirSource(path), nil
+	case SourceZip:
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		return zipSource{r}, nil
+	case SourceTar:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return tarSource{f}, nil
+	case SourceFile:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return fileSource{f}, nil
+	}
+	return nil, ErrUnknownSource
+}
+
+func sourceKind(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return SourceDir
+	}
+	name := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return SourceZip
+	case strings.HasSuffix(name, ".tar"), strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return SourceTar
+	}
+	if format, _ := fileFormat(name); format != "" {
+		return SourceFile
+	}
+	return ""
+}
+
+// dirSource is a directory with the data files, the subdirectories are walked too
+type dirSource string
+
+func (d dirSource) Walk(workers int, f func(name string, r io.Reader) error) error {
+	var names []string
+	err := filepath.Walk(string(d), func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		name, err := filepath.Rel(string(d), path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(name))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return walkConcurrently(names, workers, func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+	}, f)
+}
+
+func (d dirSource) Close() error {
+	return nil
+}
+
+type zipSource struct {
+	r *zip.ReadCloser
+}
+
+func (z zipSource) Walk(workers int, f func(name string, r io.Reader) error) error {
+	files := make(map[string]*zip.File)
+	var names []string
+	for _, file := range z.r.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		files[file.Name] = file
+		names = append(names, file.Name)
+	}
+	return walkConcurrently(names, workers, func(name string) (io.ReadCloser, error) {
+		return files[name].Open()
+	}, f)
+}
+
+func (z zipSource) Close() error {
+	return z.r.Close()
+}
+
+// tarSource is read sequentially in the order of the archive
+type tarSource struct {
+	f *os.File
+}
+
+func (t tarSource) Walk(workers int, f func(name string, r io.Reader) error) error {
+	r := bufio.NewReader(t.f)
+	var archive io.Reader = r
+	if magic, err := r.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		archive = gz
+	}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if err := f(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (t tarSource) Close() error {
+	return t.f.Close()
+}
+
+// fileSource is a single data file, users_1.json or visits.ndjson.gz
+type fileSource struct {
+	f *os.File
+}
+
+func (s fileSource) Walk(workers int, f func(name string, r io.Reader) error) error {
+	return f(filepath.Base(s.f.Name()), bufio.NewReader(s.f))
+}
+
+func (s fileSource) Close() error {
+	return s.f.Close()
+}
+
+// walkConcurrently passes the files opened by open to f from up to workers goroutines, the visits go last
+func walkConcurrently(names []string, workers int, open func(name string) (io.ReadCloser, error), f func(name string, r io.Reader) error) error {
+	// visits are linked to the users and locations added before them and relinked by the ones added later,
+	// importing the visits last only saves the relinking
+	sort.SliceStable(names, func(i, j int) bool {
+		return fileEntity(names[i]) != store.EntityVisits && fileEntity(names[j]) == store.EntityVisits
+	})
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	jobs := make(chan string)
+	errs := make(chan error, len(names))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				r, err := open(name)
+				if err == nil {
+					err = f(name, r)
+					r.Close()
+				}
+				errs <- err
+			}
+		}()
+	}
+	for _, name := range names {
+		jobs <- name
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/la0rg/highloadcup/store"
+)
+
+// ndjsonFiles are testFiles as gzipped NDJSON with a stray README
+func ndjsonFiles() [][2]string {
+	var files [][2]string
+	for _, f := range testFiles {
+		records := f[1][strings.Index(f[1], "[")+1 : strings.LastIndex(f[1], "]")]
+		records = strings.Replace(records, "},", "}\n", -1)
+		var b bytes.Buffer
+		gz := gzip.NewWriter(&b)
+		gz.Write([]byte(records))
+		gz.Close()
+		name := "pipeline/" + strings.TrimSuffix(path.Base(f[0]), ".json") + ".ndjson.gz"
+		files = append(files, [2]string{name, b.String()})
+	}
+	return append(files, [2]string{"pipeline/README.md", "# data"})
+}
+
+func TestImportData_Sources(t *testing.T) {
+	files := ndjsonFiles()
+
+	dir, err := ioutil.TempDir("", "hlc-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, f := range files {
+		name := filepath.Join(dir, filepath.FromSlash(f[0]))
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if err := ioutil.WriteFile(name, []byte(f[1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tgz := tempPath(t, ".tar.gz")
+	defer os.Remove(tgz)
+	out, err := os.Create(tgz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		tw.WriteHeader(&tar.Header{Name: f[0], Mode: 0644, Size: int64(len(f[1])), Typeflag: tar.TypeReg})
+		tw.Write([]byte(f[1]))
+	}
+	tw.Close()
+	gz.Close()
+	out.Close()
+
+	zip := writeZip(t, files)
+	defer os.Remove(zip)
+
+	for _, source := range []string{dir, tgz, zip} {
+		s := store.NewStore()
+		report, err := ImportData(source, ImportOptions{Workers: 2}, s)
+		if err != nil {
+			t.Fatalf("%s: %v", source, err)
+		}
+		if report.Accepted != 7 || report.Rejected != 0 || len(report.Files) != 4 ||
+			!reflect.DeepEqual(report.Skipped, []string{"pipeline/README.md"}) {
+			t.Errorf("%s: unexpected report %+v", source, report)
+		}
+		checkLinks(t, s, source)
+	}
+
+	// the format of the flag is used for all the files
+	if _, err := ImportData(dir, ImportOptions{Format: FormatJSON}, store.NewStore()); err == nil {
+		t.Error("NDJSON files are imported as JSON")
+	}
+	if _, err := ImportData(zip, ImportOptions{Source: SourceTar}, store.NewStore()); err == nil {
+		t.Error("zip archive is imported as tar")
+	}
+	if _, err := ImportData(zip, ImportOptions{Format: "xml"}, store.NewStore()); err != ErrUnknownFormat {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+	if _, err := ImportData(dir+"/data.rar", ImportOptions{}, store.NewStore()); err != ErrUnknownSource {
+		t.Errorf("expected ErrUnknownSource, got %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrUnknownFormat = errors.New("Data format should be json or ndjson")
+	errRecordTooLong = errors.New("Imported record is too long")
+	errArraySyntax   = errors.New("Imported file should be {\"<entity>\": [<records>]}")
+)
+
+// Formats of the data files
+const (
+	// FormatJSON is {"<entity>": [<records>]} of the .json files
+	FormatJSON = "json"
+	// FormatNDJSON is a record per line of the .ndjson and .jsonl files
+	FormatNDJSON = "ndjson"
+)
+
+// maxRecordLen limits the memory used by a single decoded record
+const maxRecordLen = 1 << 20
+
+// recordDecoder returns the raw JSON records of a file one by one, io.EOF after the last one.
+// The record is valid until the next call.
+type recordDecoder interface {
+	Next() ([]byte, error)
+}
+
+func newRecordDecoder(format string, r io.Reader, entity string) (recordDecoder, error) {
+	switch format {
+	case FormatJSON:
+		return newArrayDecoder(r, entity), nil
+	case FormatNDJSON:
+		return newLineDecoder(r), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// fileFormat detects the format by the extension of the file,
+// it is empty for unknown extensions, .gz after the extension means gzipped file
+func fileFormat(name string) (format string, gzipped bool) {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, ".gz") {
+		name, gzipped = name[:len(name)-len(".gz")], true
+	}
+	switch path.Ext(name) {
+	case ".json":
+		format = FormatJSON
+	case ".ndjson", ".jsonl":
+		format = FormatNDJSON
+	}
+	return format, gzipped
+}
+
+// lineDecoder reads newline delimited records, blank lines are skipped
+type lineDecoder struct {
+	scanner *bufio.Scanner
+}
+
+func newLineDecoder(r io.Reader) *lineDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxRecordLen)
+	return &lineDecoder{scanner}
+}
+
+func (d *lineDecoder) Next() ([]byte, error) {
+	for d.scanner.Scan() {
+		line := bytes.TrimSpace(d.scanner.Bytes())
+		if len(line) > 0 {
+			return line, nil
+		}
+	}
+	switch err := d.scanner.Err(); err {
+	case nil:
+		return nil, io.EOF
+	case bufio.ErrTooLong:
+		return nil, errRecordTooLong
+	default:
+		return nil, err
+	}
+}
+
+// arrayDecoder reads the elements of the array of {"<key>": [...]} one by one,
+// only the current element is kept in memory
+type arrayDecoder struct {
+	r       *bufio.Reader
+	key     string
+	buf     []byte
+	started bool
+	done    bool
+}
+
+func newArrayDecoder(r io.Reader, key string) *arrayDecoder {
+	return &arrayDecoder{r: bufio.NewReaderSize(r, 64<<10), key: key}
+}
+
+// Next returns the raw JSON of the next element, it is valid until the next call.
+// io.EOF is returned after the last element.
+func (d *arrayDecoder) Next() ([]byte, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+	if !d.started {
+		if err := d.readHeader(); err != nil {
+			return nil, err
+		}
+		d.started = true
+		c, err := d.peek()
+		if err != nil {
+			return nil, err
+		}
+		if c == ']' {
+			d.r.ReadByte()
+			d.done = true
+			return nil, io.EOF
+		}
+	}
+	if err := d.readValue(); err != nil {
+		return nil, err
+	}
+	c, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	switch c {
+	case ',':
+	case ']':
+		d.done = true
+	default:
+		return nil, errArraySyntax
+	}
+	return d.buf, nil
+}
+
+// readHeader skips {"<key>": [
+func (d *arrayDecoder) readHeader() error {
+	if err := d.expect('{'); err != nil {
+		return err
+	}
+	if err := d.expect('"'); err != nil {
+		return err
+	}
+	key, err := d.r.ReadString('"')
+	if err != nil {
+		return err
+	}
+	if key[:len(key)-1] != d.key {
+		return fmt.Errorf("expected %q array, got %q", d.key, key[:len(key)-1])
+	}
+	if err := d.expect(':'); err != nil {
+		return err
+	}
+	return d.expect('[')
+}
+
+// readValue reads an object, a string or a scalar into buf
+func (d *arrayDecoder) readValue() error {
+	d.buf = d.buf[:0]
+	c, err := d.next()
+	if err != nil {
+		return err
+	}
+	depth := 0
+	inString, escaped := false, false
+	for {
+		if len(d.buf) >= maxRecordLen {
+			return errRecordTooLong
+		}
+		d.buf = append(d.buf, c)
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+		}
+		if depth == 0 && !inString {
+			next, err := d.r.Peek(1)
+			if err == io.EOF || (err == nil && isDelimiter(next[0])) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if depth < 0 {
+			return errArraySyntax
+		}
+		if c, err = d.r.ReadByte(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+}
+
+func isDelimiter(c byte) bool {
+	return c == ',' || c == ']' || isSpace(c)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// next returns the next byte that is not a space
+func (d *arrayDecoder) next() (byte, error) {
+	for {
+		c, err := d.r.ReadByte()
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if err != nil || !isSpace(c) {
+			return c, err
+		}
+	}
+}
+
+func (d *arrayDecoder) peek() (byte, error) {
+	c, err := d.next()
+	if err == nil {
+		d.r.UnreadByte()
+	}
+	return c, err
+}
+
+func (d *arrayDecoder) expect(c byte) error {
+	got, err := d.next()
+	if err != nil {
+		return err
+	}
+	if got != c {
+		return errArraySyntax
+	}
+	return nil
+}
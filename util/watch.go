@@ -0,0 +1,127 @@
+package util
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/la0rg/highloadcup/model"
+	"github.com/la0rg/highloadcup/store"
+	"github.com/mailru/easyjson"
+	log "github.com/sirupsen/logrus"
+)
+
+// AppliedSuffix marks the files merged by Watcher: <file>_applied keeps the import report of <file>
+const AppliedSuffix = "_applied"
+
+// Watcher merges the data files and archives dropped into a directory into the running store.
+// Records of the existing ids update them. The merge goes through the regular store mutations,
+// so the queries are served meanwhile and the changes are logged to the WAL and streamed as events.
+type Watcher struct {
+	dir     string
+	store   *store.Store
+	opts    ImportOptions
+	persist func() error
+	// seen keeps the files of the previous poll, a file is imported
+	// when it is not changed between two polls (it is written completely)
+	seen map[string]fileState
+	// failed keeps the files that are not imported until they are changed
+	failed map[string]fileState
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewWatcher creates the watcher of dir, opts apply to every merged file or archive.
+// persist makes the merged records durable (syncs the WAL or saves the snapshot),
+// the marker of a file is written only after it, so the file is merged again if the process crashes before.
+func NewWatcher(dir string, store *store.Store, opts ImportOptions, persist func() error) *Watcher {
+	opts.Merge = true
+	return &Watcher{
+		dir:     dir,
+		store:   store,
+		opts:    opts,
+		persist: persist,
+		seen:    make(map[string]fileState),
+		failed:  make(map[string]fileState),
+	}
+}
+
+// Run polls the directory every interval forever
+func (w *Watcher) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		w.Poll()
+	}
+}
+
+// Poll merges the new files of the directory in the order of their names and returns their reports.
+// A file is merged by the second poll that sees it unchanged. The file is merged again after a restart
+// if the process stops before its marker is written, the records of the existing ids update them then.
+// The files that could not be persisted are reported too, but they are not marked.
+func (w *Watcher) Poll() []*model.ImportReport {
+	infos, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		log.Errorf("Could not read the watched directory %s: %v", w.dir, err)
+		return nil
+	}
+	names := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		names[info.Name()] = true
+	}
+
+	var reports []*model.ImportReport
+	seen := make(map[string]fileState)
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, AppliedSuffix) || names[name+AppliedSuffix] {
+			continue
+		}
+		path := filepath.Join(w.dir, name)
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		prev, ok := w.seen[name]
+		seen[name] = state
+		if !ok || prev != state {
+			continue
+		}
+		if failed, ok := w.failed[name]; ok && failed == state {
+			continue
+		}
+		if sourceKind(path) == "" {
+			log.Warnf("Skipped %s: unknown data file or archive", path)
+			w.failed[name] = state
+			continue
+		}
+
+		report, err := ImportData(path, w.opts, w.store)
+		if err != nil {
+			log.Errorf("Could not merge %s: %v", path, err)
+			w.failed[name] = state
+			continue
+		}
+		delete(w.failed, name)
+		LogImportReport(report)
+		reports = append(reports, report)
+		if err := w.persist(); err != nil {
+			// the file is merged again after a restart
+			log.Errorf("Could not persist the records of %s: %v", path, err)
+			w.failed[name] = state
+			continue
+		}
+		b, err := easyjson.Marshal(report)
+		if err == nil {
+			err = ioutil.WriteFile(path+AppliedSuffix, b, 0644)
+		}
+		if err != nil {
+			// the file is merged again after a restart
+			log.Errorf("Could not write the marker of %s: %v", path, err)
+			w.failed[name] = state
+		}
+	}
+	w.seen = seen
+	return reports
+}
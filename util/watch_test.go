@@ -0,0 +1,95 @@
+package util
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/la0rg/highloadcup/store"
+)
+
+func TestWatcher_Poll(t *testing.T) {
+	path := writeZip(t, testFiles)
+	defer os.Remove(path)
+	s := store.NewStore()
+	if _, err := ImportData(path, ImportOptions{}, s); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "hlc-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var persisted int
+	var errPersist error
+	persist := func() error {
+		persisted++
+		return errPersist
+	}
+	w := NewWatcher(dir, s, ImportOptions{}, persist)
+
+	write := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("visits_3.json", `{"visits": [{"id": 1, "mark": 2}, {"id": 4, "location": 2, "user": 2, "visited_at": 400, "mark": 4}]}`)
+	write("README", "# deltas")
+	if reports := w.Poll(); len(reports) != 0 {
+		t.Errorf("file is merged by the first poll, got %+v", reports)
+	}
+	reports := w.Poll()
+	if len(reports) != 1 || reports[0].Accepted != 2 || reports[0].Updated != 1 || reports[0].Rejected != 0 {
+		t.Fatalf("expected 2 accepted records with 1 update, got %+v", reports)
+	}
+	if v, _ := s.GetVisitByID(1); v.Mark.V != 2 || v.UserID.V != 1 {
+		t.Errorf("expected the updated mark of visit 1, got %+v", v)
+	}
+	if v, ok := s.GetVisitByID(4); !ok || v.VisitedAt.V != 400 {
+		t.Errorf("expected new visit 4, got %+v", v)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "visits_3.json"+AppliedSuffix)); err != nil || persisted != 1 {
+		t.Errorf("marker is not written after the records are persisted: %v, %d", err, persisted)
+	}
+
+	// the marked file is not merged again, a new watcher is the restarted process
+	w = NewWatcher(dir, s, ImportOptions{}, persist)
+	w.Poll()
+	if reports := w.Poll(); len(reports) != 0 {
+		t.Errorf("applied file is merged again: %+v", reports)
+	}
+
+	write("users_2.json", `{"users": [{"id": 2, "first_name": "b"}, {"id": 3]}`)
+	w.Poll()
+	if reports := w.Poll(); len(reports) != 0 {
+		t.Errorf("broken file is merged: %+v", reports)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "users_2.json"+AppliedSuffix)); err == nil {
+		t.Error("broken file is marked")
+	}
+
+	// the records that are not persisted are lost on a crash, so the file is merged again after a restart
+	errPersist = errors.New("disk is full")
+	write("users_3.json", `{"users": [{"id": 2, "first_name": "c"}]}`)
+	w.Poll()
+	if reports := w.Poll(); len(reports) != 1 || persisted != 2 {
+		t.Errorf("expected 1 merged file, got %+v", reports)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "users_3.json"+AppliedSuffix)); err == nil {
+		t.Error("file that is not persisted is marked")
+	}
+	if reports := w.Poll(); len(reports) != 0 {
+		t.Errorf("file that is not persisted is merged again before a restart: %+v", reports)
+	}
+	errPersist = nil
+	w = NewWatcher(dir, s, ImportOptions{}, persist)
+	w.Poll()
+	if reports := w.Poll(); len(reports) != 1 || reports[0].Updated != 1 {
+		t.Errorf("expected the file merged again after a restart, got %+v", reports)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "users_3.json"+AppliedSuffix)); err != nil {
+		t.Errorf("marker is not written: %v", err)
+	}
+}